@@ -1,8 +1,16 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
 	"log"
+	"os"
+	"strconv"
 	"time"
+	_ "time/tzdata" // Para cargar America/Mexico_City aunque el contenedor no traiga zoneinfo
 
 	"github.com/gin-gonic/gin"
 
@@ -10,8 +18,12 @@ import (
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 
+	"github.com/scorazag/gopayhub/internal/adapters/cache"
+	"github.com/scorazag/gopayhub/internal/adapters/connector"
+	"github.com/scorazag/gopayhub/internal/adapters/fx"
 	"github.com/scorazag/gopayhub/internal/adapters/handler/http"
 	"github.com/scorazag/gopayhub/internal/adapters/handler/http/middleware"
+	"github.com/scorazag/gopayhub/internal/adapters/notifier"
 
 	// 2. Le damos el alias 'repoPostgres' a TU carpeta
 	repoPostgres "github.com/scorazag/gopayhub/internal/adapters/repository/postgres"
@@ -44,6 +56,19 @@ func main() {
 		log.Fatalf("Error al habilitar la extensión uuid-ossp: %v", err)
 	}
 
+	// Las cuentas del libro mayor ahora son únicas por código + moneda
+	if err := db.Exec(`DROP INDEX IF EXISTS idx_ledger_accounts_code`).Error; err != nil {
+		log.Fatalf("Error al actualizar índices del libro mayor: %v", err)
+	}
+
+	// Las llaves de idempotencia ahora son por cliente y operación; las viejas (globales) no se pueden
+	// migrar a un cliente, y de todos modos solo sirven mientras dura la ventana de reintentos
+	if db.Migrator().HasTable(&domain.IdempotencyKey{}) && !db.Migrator().HasColumn(&domain.IdempotencyKey{}, "client_id") {
+		if err := db.Migrator().DropTable(&domain.IdempotencyKey{}); err != nil {
+			log.Fatalf("Error al recrear la tabla de llaves de idempotencia: %v", err)
+		}
+	}
+
 	err = db.AutoMigrate(
 		&domain.Client{},
 		&domain.Merchant{},
@@ -51,6 +76,17 @@ func main() {
 		&domain.IdempotencyKey{},
 		&domain.Deposit{},
 		&domain.CashOut{},
+		&domain.Refund{},
+		&domain.FeeSchedule{},
+		&domain.FeeTier{},
+		&domain.Fee{},
+		&domain.TaxRate{},
+		&domain.Limit{},
+		&domain.LedgerAccount{},
+		&domain.JournalEntry{},
+		&domain.Posting{},
+		&domain.PaymentJob{},
+		&domain.RefundJob{},
 	)
 	if err != nil {
 		log.Fatalf("Error durante la migración de la DB: %v", err)
@@ -63,41 +99,190 @@ func main() {
 	// El repositorio solo sabe interactuar con la DB
 	repo := repoPostgres.NewPaymentRepository(db)
 
+	// Tasas de IVA por región (16% general, 8% fronteriza); se pueden editar después en la tabla tax_rates
+	if err := repo.SeedTaxRates(); err != nil {
+		log.Fatalf("Error al dar de alta las tasas de IVA: %v", err)
+	}
+
+	// Límites por omisión (tope de depósito en efectivo); se pueden editar después en la tabla limits
+	if err := repo.SeedLimits(); err != nil {
+		log.Fatalf("Error al dar de alta los límites: %v", err)
+	}
+
+	// El libro mayor es la fuente de verdad de los saldos; generamos pólizas para operaciones viejas
+	if err := repo.BackfillLedger(); err != nil {
+		log.Fatalf("Error al generar pólizas del libro mayor: %v", err)
+	}
+
+	// Conector hacia los billers: HTTP/JSON a su IntegrationURL (los que no tienen URL solo quedan en el log).
+	// MERCHANT_TIMEOUT aplica a los billers sin TimeoutMS; el circuit breaker deja de llamar a un biller caído.
+	merchantTimeout := getDuration("MERCHANT_TIMEOUT", 10*time.Second)
+	if merchantTimeout > domain.MaxMerchantTimeout {
+		log.Fatalf("MERCHANT_TIMEOUT (%s) no puede pasar de %s: los jobs de la cola se apartan por ese tiempo", merchantTimeout, domain.MaxMerchantTimeout)
+	}
+	merchantConnector := connector.NewResilientConnector(connector.NewHTTPConnector(merchantTimeout), connector.ResilienceConfig{
+		FailureThreshold: getInt("MERCHANT_BREAKER_FAILURES", 5),
+		OpenFor:          getDuration("MERCHANT_BREAKER_OPEN", 30*time.Second),
+		MaxAttempts:      getInt("MERCHANT_INQUIRY_ATTEMPTS", 3),
+		Backoff:          getDuration("MERCHANT_RETRY_BACKOFF", 200*time.Millisecond),
+	})
+
+	// Tipos de cambio para merchants que cobran en otra moneda (por ahora de un archivo fijo)
+	fxProvider, err := fx.NewFileProvider(getEnv("FX_RATES_FILE", "config/fx_rates.json"))
+	if err != nil {
+		log.Fatalf("Error al cargar los tipos de cambio: %v", err)
+	}
+
 	// Servicio (Capa de Core/Negocio)
 	// El servicio recibe el repositorio, NO la DB.
-	paymentService := services.NewPaymentService(repo)
-	depositService := services.NewDepositService(repo)
-	cashoutService := services.NewCashOutService(repo)
+	businessLocation := loadBusinessLocation()
+	limits := services.NewLimitChecker(businessLocation)
+	references := loadReferenceValidator(getEnv("REFERENCE_RULES_FILE", "config/reference_rules.json"), businessLocation)
+	// Las consultas de recibos se guardan poco tiempo; el pago puede ligarse a una mientras siga vigente
+	bills := cache.NewMemoryBillCache()
+	billService := services.NewBillService(repo, merchantConnector, bills, getDuration("BILL_INQUIRY_TTL", 5*time.Minute), references)
+	paymentService := services.NewPaymentService(repo, fxProvider, limits, merchantConnector, bills, references, notifier.NewWebhookNotifier(getDuration("WEBHOOK_TIMEOUT", 5*time.Second)), merchantConnector)
+	depositService := services.NewDepositService(repo, limits)
+	cashoutService := services.NewCashOutService(repo, limits)
+	refundService := services.NewRefundService(repo, merchantConnector)
+	voidService := services.NewVoidService(repo, loadVoidPolicy(businessLocation))
+	queryService := services.NewQueryService(repo)
+	accountService := services.NewAccountService(repo)
+	authorizationService := services.NewAuthorizationService(repo, fxProvider, limits, getDuration("HOLD_TTL", 30*time.Minute), references, merchantConnector, merchantConnector)
+
+	// Jobs en segundo plano
+	idempotencyRetention := getDuration("IDEMPOTENCY_RETENTION", 24*time.Hour)
+	go runEvery(getDuration("IDEMPOTENCY_PURGE_INTERVAL", time.Hour), "purgar llaves de idempotencia vencidas", func() error {
+		purged, err := repo.PurgeExpiredIdempotencyKeys(time.Now())
+		if purged > 0 {
+			log.Printf("Se purgaron %d llaves de idempotencia vencidas", purged)
+		}
+		return err
+	})
+	go runEvery(getDuration("HOLD_EXPIRY_INTERVAL", time.Minute), "liberar retenciones vencidas", func() error {
+		released, err := authorizationService.ExpireHolds()
+		if released > 0 {
+			log.Printf("Se liberaron %d retenciones vencidas", released)
+		}
+		return err
+	})
+
+	// Pagos asíncronos (Prefer: respond-async): la cola vive en la BD, así que lo pendiente se retoma al reiniciar
+	go services.RunPaymentWorkers(context.Background(), paymentService, getInt("PAYMENT_WORKERS", 4), getDuration("PAYMENT_QUEUE_POLL", time.Second))
+	// Devoluciones que el biller no contestó; son pocas, con un worker basta
+	go services.RunRefundWorkers(context.Background(), refundService, getInt("REFUND_WORKERS", 1), getDuration("PAYMENT_QUEUE_POLL", time.Second))
 
 	// Handler (Capa de Adaptadores/Gin)
 	// El handler recibe el servicio.
-	paymentHandler := http.NewPaymentHandler(paymentService)
-	depositHandler := http.NewDepositHandler(depositService)
-	cashoutHandler := http.NewCashOutHandler(cashoutService)
+	handlers := http.Handlers{
+		Payment:       http.NewPaymentHandler(paymentService),
+		Deposit:       http.NewDepositHandler(depositService),
+		CashOut:       http.NewCashOutHandler(cashoutService),
+		Refund:        http.NewRefundHandler(refundService),
+		Void:          http.NewVoidHandler(voidService),
+		Authorization: http.NewAuthorizationHandler(authorizationService),
+		Query:         http.NewQueryHandler(queryService, businessLocation),
+		Account:       http.NewAccountHandler(accountService, businessLocation),
+		Bill:          http.NewBillHandler(billService),
+		Ops:           http.NewOpsHandler(merchantConnector),
+	}
 
 	// 4. Configuración de Rutas y Servidor Gin
-
-	// Grupo de rutas API
+	// Las rutas viven en el paquete http junto con su documento OpenAPI.
+	// Un reintento con la misma X-Idempotency-Key recibe la respuesta original en todas las rutas.
 	r := gin.Default()
-
-	api := r.Group("/api/v1")
-	{
-		// El health check lo dejamos fuera del auth para que AWS/Docker puedan revisarlo
-		api.GET("/health", func(c *gin.Context) {
-			c.JSON(200, gin.H{"status": "OK"})
-		})
-
-		// Aplicamos el middleware a partir de aquí
-		api.Use(middleware.AuthMiddleware(repo))
-
-		// Esta ruta ahora está protegida
-		api.POST("/transactions", paymentHandler.ProcessTransaction)
-		api.POST("/deposits", depositHandler.ProcessDeposit)
-		api.POST("/cashouts", cashoutHandler.ProcessCashOut)
+	opsKey := getEnv("OPS_API_KEY", "")
+	if opsKey == "" {
+		log.Println("OPS_API_KEY no está configurada; las rutas /ops quedan cerradas")
 	}
+	http.RegisterRoutes(r, handlers, middleware.OpsAuth(opsKey), middleware.AuthMiddleware(repo), middleware.Idempotency(repo, idempotencyRetention))
 
 	log.Println("Servidor GoPayHub iniciado en :8080")
 	if err := r.Run(":8080"); err != nil {
 		log.Fatalf("Error al iniciar el servidor: %v", err)
 	}
 }
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func getDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("%s inválido (%q): %v", key, value, err)
+	}
+	return d
+}
+
+// getInt lee un entero de al menos 1: todos son conteos (workers, fallas, intentos) y con cero
+// el servicio arrancaría sin hacer nada, por ejemplo sin workers que vacíen la cola de pagos
+func getInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		log.Fatalf("%s inválido (%q): tiene que ser un entero mayor o igual a 1", key, value)
+	}
+	return n
+}
+
+// runEvery ejecuta job cada interval; los errores se registran y el job sigue corriendo
+func runEvery(interval time.Duration, name string, job func() error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := job(); err != nil {
+			log.Printf("Error en job %q: %v", name, err)
+		}
+	}
+}
+
+// loadBusinessLocation lee la zona horaria del día hábil (anulaciones y límites diarios/mensuales)
+func loadBusinessLocation() *time.Location {
+	loc, err := time.LoadLocation(getEnv("BUSINESS_TIMEZONE", "America/Mexico_City"))
+	if err != nil {
+		log.Fatalf("Zona horaria inválida: %v", err)
+	}
+	return loc
+}
+
+// loadVoidPolicy lee la ventana de anulación: mismo día hábil y antes de VOID_CUTOFF (HH:MM)
+func loadVoidPolicy(loc *time.Location) services.VoidPolicy {
+	var hour, minute int
+	cutoff := getEnv("VOID_CUTOFF", "23:00")
+	if _, err := fmt.Sscanf(cutoff, "%d:%d", &hour, &minute); err != nil {
+		log.Fatalf("VOID_CUTOFF inválido (%q), se espera HH:MM: %v", cutoff, err)
+	}
+	return services.VoidPolicy{CutoffHour: hour, CutoffMinute: minute, Location: loc}
+}
+
+// loadReferenceValidator lee las reglas de referencia por merchant y por tipo de servicio.
+// Sin archivo no hay reglas: solo se pide que la referencia no venga vacía.
+func loadReferenceValidator(path string, loc *time.Location) *services.ReferenceValidator {
+	var rules domain.ReferenceRules
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		log.Printf("No hay reglas de referencia en %s; solo se exige que la referencia no venga vacía", path)
+		data = []byte("{}")
+	} else if err != nil {
+		log.Fatalf("Error al leer las reglas de referencia: %v", err)
+	}
+	if err := json.Unmarshal(data, &rules); err != nil {
+		log.Fatalf("Archivo de reglas de referencia inválido: %v", err)
+	}
+	validator, err := services.NewReferenceValidator(rules, loc)
+	if err != nil {
+		log.Fatalf("Reglas de referencia inválidas: %v", err)
+	}
+	return validator
+}
@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/scorazag/gopayhub/internal/core/domain"
+)
+
+type billKey struct {
+	clientID   uint
+	merchantID uint
+	reference  string
+}
+
+func keyOf(bill *domain.Bill) billKey {
+	return billKey{bill.ClientID, bill.MerchantID, bill.Reference}
+}
+
+// MemoryBillCache implementa ports.BillCache en memoria del proceso.
+// Las consultas viven minutos, así que perderlas al reiniciar solo obliga a consultar otra vez.
+type MemoryBillCache struct {
+	mu    sync.Mutex
+	byID  map[uuid.UUID]*domain.Bill
+	byRef map[billKey]*domain.Bill
+	now   func() time.Time
+}
+
+func NewMemoryBillCache() *MemoryBillCache {
+	return &MemoryBillCache{byID: map[uuid.UUID]*domain.Bill{}, byRef: map[billKey]*domain.Bill{}, now: time.Now}
+}
+
+// Take quita la consulta con el candado tomado: de dos pagos con la misma consulta solo uno la obtiene
+func (c *MemoryBillCache) Take(inquiryID uuid.UUID, clientID uint) (*domain.Bill, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	bill, ok := c.fresh(c.byID[inquiryID])
+	if !ok || bill.ClientID != clientID {
+		return nil, false
+	}
+	c.remove(c.byID[inquiryID])
+	return bill, true
+}
+
+func (c *MemoryBillCache) Find(clientID uint, merchantID uint, reference string) (*domain.Bill, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.fresh(c.byRef[billKey{clientID, merchantID, reference}])
+}
+
+// Put guarda la consulta y aprovecha para tirar las vencidas, así el mapa no crece sin límite
+func (c *MemoryBillCache) Put(bill *domain.Bill) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	for _, b := range c.byID {
+		if b.Expired(now) {
+			c.remove(b)
+		}
+	}
+	c.byID[bill.InquiryID] = bill
+	c.byRef[keyOf(bill)] = bill
+}
+
+// remove quita la consulta de los dos índices; la referencia solo si no apunta ya a otra consulta
+func (c *MemoryBillCache) remove(bill *domain.Bill) {
+	delete(c.byID, bill.InquiryID)
+	if c.byRef[keyOf(bill)] == bill {
+		delete(c.byRef, keyOf(bill))
+	}
+}
+
+// fresh regresa una copia para que nadie modifique lo guardado
+func (c *MemoryBillCache) fresh(bill *domain.Bill) (*domain.Bill, bool) {
+	if bill == nil || bill.Expired(c.now()) {
+		return nil, false
+	}
+	copied := *bill
+	return &copied, true
+}
@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryBillCache_ExpiresInquiries(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	c := NewMemoryBillCache()
+	c.now = func() time.Time { return now }
+
+	bill := &domain.Bill{InquiryID: uuid.New(), MerchantID: 1, ClientID: 7, Reference: "CFE-1", ExpiresAt: now.Add(5 * time.Minute)}
+	c.Put(bill)
+
+	got, ok := c.Find(7, 1, "CFE-1")
+	assert.True(t, ok)
+	assert.Equal(t, bill, got)
+	_, ok = c.Find(7, 2, "CFE-1")
+	assert.False(t, ok)
+
+	now = now.Add(5 * time.Minute)
+	_, ok = c.Take(bill.InquiryID, 7)
+	assert.False(t, ok)
+	_, ok = c.Find(7, 1, "CFE-1")
+	assert.False(t, ok)
+
+	// La siguiente consulta se lleva las vencidas
+	c.Put(&domain.Bill{InquiryID: uuid.New(), MerchantID: 1, ClientID: 7, Reference: "CFE-2", ExpiresAt: now.Add(time.Minute)})
+	assert.Len(t, c.byID, 1)
+	assert.Len(t, c.byRef, 1)
+}
+
+func TestMemoryBillCache_InquiriesBelongToTheClient(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	c := NewMemoryBillCache()
+	c.now = func() time.Time { return now }
+
+	bill := &domain.Bill{InquiryID: uuid.New(), MerchantID: 1, ClientID: 7, Reference: "CFE-1", ExpiresAt: now.Add(5 * time.Minute)}
+	c.Put(bill)
+
+	// Otro cliente ni la encuentra ni se la puede llevar
+	_, ok := c.Find(8, 1, "CFE-1")
+	assert.False(t, ok)
+	_, ok = c.Take(bill.InquiryID, 8)
+	assert.False(t, ok)
+
+	// Ligada a un pago ya no sirve para otro
+	got, ok := c.Take(bill.InquiryID, 7)
+	assert.True(t, ok)
+	assert.Equal(t, bill, got)
+	_, ok = c.Take(bill.InquiryID, 7)
+	assert.False(t, ok)
+	_, ok = c.Find(7, 1, "CFE-1")
+	assert.False(t, ok)
+}
@@ -0,0 +1,239 @@
+package connector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/scorazag/gopayhub/internal/core/domain"
+)
+
+// paymentRequest es lo que le mandamos al biller en POST {IntegrationURL}/payments
+type paymentRequest struct {
+	TransactionID string       `json:"transaction_id"`
+	Reference     string       `json:"reference"`
+	Amount        domain.Money `json:"amount"`
+	Currency      string       `json:"currency"`
+	InquiryID     string       `json:"inquiry_id,omitempty"` // Si se cobró con una consulta del recibo
+}
+
+// paymentResponse es la respuesta del biller:
+//
+//	{"status": "APPROVED", "authorization_number": "CFE-000123"}
+//	{"status": "REJECTED", "message": "referencia vencida"}
+type paymentResponse struct {
+	Status              string `json:"status"`
+	AuthorizationNumber string `json:"authorization_number"`
+	Message             string `json:"message"`
+}
+
+// refundRequest es lo que le mandamos al biller en POST {IntegrationURL}/refunds
+type refundRequest struct {
+	RefundID      string       `json:"refund_id"`
+	TransactionID string       `json:"transaction_id"`
+	Reference     string       `json:"reference"`
+	Amount        domain.Money `json:"amount"`
+	Currency      string       `json:"currency"`
+	Reason        string       `json:"reason,omitempty"`
+}
+
+// billResponse es la respuesta de GET {IntegrationURL}/bills?reference=...; 404 si no hay recibo pendiente:
+//
+//	{"customer_name": "María González", "amount_due": "845.50", "currency": "MXN", "due_date": "2026-10-30"}
+type billResponse struct {
+	CustomerName string       `json:"customer_name"`
+	AmountDue    domain.Money `json:"amount_due"`
+	Currency     string       `json:"currency"`
+	DueDate      string       `json:"due_date"` // AAAA-MM-DD
+}
+
+// HTTPConnector implementa ports.MerchantConnector con una API HTTP/JSON en el IntegrationURL de cada biller.
+// Los billers sin IntegrationURL siguen yendo al LogConnector.
+type HTTPConnector struct {
+	client   *http.Client
+	timeout  time.Duration // Para los billers sin TimeoutMS
+	fallback *LogConnector
+}
+
+func NewHTTPConnector(timeout time.Duration) *HTTPConnector {
+	return &HTTPConnector{client: &http.Client{}, timeout: timeout, fallback: NewLogConnector()}
+}
+
+// NotifyPayment manda el pago en la moneda en que cobra el biller.
+// 2xx trae la decisión del biller; un 4xx es un rechazo; 5xx, 408, 429 o un error de red es que no contestó.
+func (c *HTTPConnector) NotifyPayment(merchant *domain.Merchant, tx *domain.Transaction) (*domain.PaymentConfirmation, error) {
+	if merchant.IntegrationURL == "" {
+		return c.fallback.NotifyPayment(merchant, tx)
+	}
+
+	req := paymentRequest{TransactionID: tx.ID.String(), Reference: tx.Reference, Amount: tx.Amount, Currency: tx.Currency}
+	if tx.SettlementCurrency != "" {
+		req.Amount, req.Currency = tx.SettlementAmount, tx.SettlementCurrency
+	}
+	if tx.BillInquiryID != nil {
+		req.InquiryID = tx.BillInquiryID.String()
+	}
+
+	var res paymentResponse
+	status, err := c.post(merchant, "/payments", tx.ID.String(), req, &res)
+	if err != nil {
+		return nil, err
+	}
+	if status < 400 && res.Status == "" {
+		return nil, fmt.Errorf("%s contestó sin status", merchant.Name)
+	}
+	if status >= 400 || !strings.EqualFold(res.Status, "APPROVED") {
+		return &domain.PaymentConfirmation{Approved: false, Message: rejectionMessage(res.Message, status)}, nil
+	}
+	return &domain.PaymentConfirmation{Approved: true, AuthorizationNumber: res.AuthorizationNumber}, nil
+}
+
+// NotifyRefund avisa de la devolución. Un 4xx es domain.ErrRefundRejected y la devolución queda FAILED;
+// no contestar es domain.ErrMerchantUnavailable y se le vuelve a preguntar con el mismo ID
+func (c *HTTPConnector) NotifyRefund(merchant *domain.Merchant, tx *domain.Transaction, refund *domain.Refund) error {
+	if merchant.IntegrationURL == "" {
+		return c.fallback.NotifyRefund(merchant, tx, refund)
+	}
+
+	req := refundRequest{
+		RefundID:      refund.ID.String(),
+		TransactionID: tx.ID.String(),
+		Reference:     tx.Reference,
+		Amount:        refund.Amount,
+		Currency:      refund.Currency,
+		Reason:        refund.Reason,
+	}
+	if refund.SettlementCurrency != "" {
+		req.Amount, req.Currency = refund.SettlementAmount, refund.SettlementCurrency
+	}
+
+	var res paymentResponse
+	status, err := c.post(merchant, "/refunds", refund.ID.String(), req, &res)
+	if err != nil {
+		return fmt.Errorf("%w: %w", domain.ErrMerchantUnavailable, err)
+	}
+	if status >= 400 {
+		return fmt.Errorf("%w: %s: %s", domain.ErrRefundRejected, merchant.Name, rejectionMessage(res.Message, status))
+	}
+	return nil
+}
+
+// InquireBill consulta el recibo pendiente; 404 es que no hay y cualquier otra falla es que no contestó
+func (c *HTTPConnector) InquireBill(merchant *domain.Merchant, reference string) (*domain.Bill, error) {
+	if merchant.IntegrationURL == "" {
+		return c.fallback.InquireBill(merchant, reference)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, endpoint(merchant, "/bills")+"?"+url.Values{"reference": {reference}}.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("IntegrationURL inválido de %s: %w", merchant.Name, err)
+	}
+	var res billResponse
+	status, err := c.do(merchant, req, &res)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusNotFound {
+		return nil, domain.ErrBillNotFound
+	}
+	if status >= 400 {
+		return nil, fmt.Errorf("%s contestó HTTP %d a la consulta", merchant.Name, status)
+	}
+
+	bill := &domain.Bill{CustomerName: res.CustomerName, AmountDue: res.AmountDue, Currency: strings.ToUpper(res.Currency)}
+	if res.DueDate != "" {
+		dueDate, err := time.Parse(time.DateOnly, res.DueDate)
+		if err != nil {
+			return nil, fmt.Errorf("%s: fecha límite inválida %q", merchant.Name, res.DueDate)
+		}
+		bill.DueDate = &dueDate
+	}
+	return bill, nil
+}
+
+// post manda body como JSON; el biller recibe idemKey para reconocer el mismo aviso si llega dos veces
+func (c *HTTPConnector) post(merchant *domain.Merchant, path, idemKey string, body, out any) (int, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoint(merchant, path), bytes.NewReader(payload))
+	if err != nil {
+		return 0, fmt.Errorf("IntegrationURL inválido de %s: %w", merchant.Name, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Idempotency-Key", idemKey)
+	return c.do(merchant, req, out)
+}
+
+// do manda la petición y decodifica la respuesta en out si trae JSON.
+// Regresa error solo cuando no hubo una respuesta definitiva del biller.
+func (c *HTTPConnector) do(merchant *domain.Merchant, req *http.Request, out any) (int, error) {
+	// El timeout cubre también leer la respuesta
+	ctx, cancel := context.WithTimeout(req.Context(), c.timeoutFor(merchant))
+	defer cancel()
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		// El texto de url.Error trae el IntegrationURL; el error llega hasta el cliente y al estado del circuito
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return 0, fmt.Errorf("%s no contestó: %w", merchant.Name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests {
+		return resp.StatusCode, &statusError{merchant: merchant.Name, status: resp.StatusCode}
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return resp.StatusCode, fmt.Errorf("%s: respuesta incompleta: %w", merchant.Name, err)
+	}
+	// Un 4xx puede venir en texto plano; solo un 2xx con cuerpo tiene que traer JSON válido
+	if len(data) == 0 {
+		return resp.StatusCode, nil
+	}
+	if err := json.Unmarshal(data, out); err != nil && resp.StatusCode < 400 {
+		return resp.StatusCode, fmt.Errorf("%s: respuesta inválida: %w", merchant.Name, err)
+	}
+	return resp.StatusCode, nil
+}
+
+// timeoutFor regresa cuánto esperamos al biller: el suyo si lo tiene configurado, si no el general.
+// Nunca más de domain.MaxMerchantTimeout, que es lo que duran apartados los jobs de la cola.
+func (c *HTTPConnector) timeoutFor(merchant *domain.Merchant) time.Duration {
+	timeout := c.timeout
+	if merchant.TimeoutMS > 0 {
+		timeout = time.Duration(merchant.TimeoutMS) * time.Millisecond
+	}
+	return min(timeout, domain.MaxMerchantTimeout)
+}
+
+func endpoint(merchant *domain.Merchant, path string) string {
+	return strings.TrimRight(merchant.IntegrationURL, "/") + path
+}
+
+func rejectionMessage(message string, status int) string {
+	if message != "" {
+		return message
+	}
+	return fmt.Sprintf("rechazado por el biller (HTTP %d)", status)
+}
+
+// statusError es un biller que contestó con un estado que no es respuesta (5xx, 408, 429)
+type statusError struct {
+	merchant string
+	status   int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s contestó HTTP %d", e.merchant, e.status)
+}
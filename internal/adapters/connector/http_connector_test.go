@@ -0,0 +1,142 @@
+package connector
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+// newBiller levanta un biller de prueba que contesta status y body en /payments
+func newBiller(t *testing.T, status int, body string, got *paymentRequest) *domain.Merchant {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/payments", r.URL.Path)
+		if got != nil {
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(got))
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return &domain.Merchant{ID: 1, Name: "CFE", IntegrationURL: srv.URL + "/"}
+}
+
+func TestHTTPConnector_NotifyPayment(t *testing.T) {
+	tx := &domain.Transaction{ID: uuid.New(), Amount: domain.MustParseMoney("20"), Currency: "USD", Reference: "CFE-1"}
+	tx.SettlementAmount, tx.SettlementCurrency = domain.MustParseMoney("341"), "MXN"
+	c := NewHTTPConnector(time.Second)
+
+	var got paymentRequest
+	approved, err := c.NotifyPayment(newBiller(t, http.StatusOK, `{"status":"APPROVED","authorization_number":"AUT-9"}`, &got), tx)
+	assert.NoError(t, err)
+	assert.Equal(t, &domain.PaymentConfirmation{Approved: true, AuthorizationNumber: "AUT-9"}, approved)
+	// El biller recibe el monto en su moneda
+	assert.Equal(t, paymentRequest{TransactionID: tx.ID.String(), Reference: "CFE-1", Amount: domain.MustParseMoney("341"), Currency: "MXN"}, got)
+
+	rejected, err := c.NotifyPayment(newBiller(t, http.StatusOK, `{"status":"REJECTED","message":"referencia vencida"}`, nil), tx)
+	assert.NoError(t, err)
+	assert.False(t, rejected.Approved)
+	assert.Equal(t, "referencia vencida", rejected.Message)
+
+	notFound, err := c.NotifyPayment(newBiller(t, http.StatusNotFound, "", nil), tx)
+	assert.NoError(t, err)
+	assert.False(t, notFound.Approved)
+
+	// Sin respuesta definitiva no hay confirmación
+	_, err = c.NotifyPayment(newBiller(t, http.StatusBadGateway, "", nil), tx)
+	assert.Error(t, err)
+	_, err = c.NotifyPayment(newBiller(t, http.StatusOK, "<html>", nil), tx)
+	assert.Error(t, err)
+}
+
+func TestHTTPConnector_WithoutIntegrationURLApproves(t *testing.T) {
+	tx := &domain.Transaction{ID: uuid.New(), Amount: domain.MustParseMoney("20"), Currency: "MXN"}
+
+	confirmation, err := NewHTTPConnector(time.Second).NotifyPayment(&domain.Merchant{Name: "Sin integración"}, tx)
+
+	assert.NoError(t, err)
+	assert.True(t, confirmation.Approved)
+}
+
+func TestHTTPConnector_InquireBill(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, "/bills", r.URL.Path)
+		if r.URL.Query().Get("reference") == "CFE 000" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"customer_name":"María González","amount_due":845.5,"currency":"mxn","due_date":"2026-10-30"}`))
+	}))
+	defer srv.Close()
+	merchant := &domain.Merchant{Name: "CFE", IntegrationURL: srv.URL}
+	c := NewHTTPConnector(time.Second)
+
+	bill, err := c.InquireBill(merchant, "CFE-123")
+	assert.NoError(t, err)
+	assert.Equal(t, "María González", bill.CustomerName)
+	assert.Equal(t, domain.MustParseMoney("845.50"), bill.AmountDue)
+	assert.Equal(t, "MXN", bill.Currency)
+	assert.Equal(t, time.Date(2026, 10, 30, 0, 0, 0, 0, time.UTC), *bill.DueDate)
+
+	_, err = c.InquireBill(merchant, "CFE 000")
+	assert.ErrorIs(t, err, domain.ErrBillNotFound)
+}
+
+func TestHTTPConnector_MerchantTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte(`{"status":"APPROVED","authorization_number":"AUT-9"}`))
+	}))
+	defer srv.Close()
+	tx := &domain.Transaction{ID: uuid.New(), Amount: domain.MustParseMoney("20"), Currency: "MXN"}
+	c := NewHTTPConnector(time.Second)
+
+	// El biller con su propio timeout corto se da por caído; con el general sí alcanza a contestar
+	_, err := c.NotifyPayment(&domain.Merchant{Name: "Lento", IntegrationURL: srv.URL, TimeoutMS: 20}, tx)
+	assert.Error(t, err)
+	// El error llega al cliente: no trae la URL del biller
+	assert.NotContains(t, err.Error(), srv.URL)
+	confirmation, err := c.NotifyPayment(&domain.Merchant{Name: "Lento", IntegrationURL: srv.URL}, tx)
+	assert.NoError(t, err)
+	assert.True(t, confirmation.Approved)
+}
+
+func TestHTTPConnector_TimeoutIsCapped(t *testing.T) {
+	c := NewHTTPConnector(10 * time.Second)
+
+	assert.Equal(t, 10*time.Second, c.timeoutFor(&domain.Merchant{}))
+	assert.Equal(t, 30*time.Second, c.timeoutFor(&domain.Merchant{TimeoutMS: 30000}))
+	// Un timeout mal capturado no puede rebasar lo que duran apartados los jobs de la cola
+	assert.Equal(t, domain.MaxMerchantTimeout, c.timeoutFor(&domain.Merchant{TimeoutMS: 60 * 60 * 1000}))
+}
+
+func TestHTTPConnector_NotifyRefund(t *testing.T) {
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/refunds", r.URL.Path)
+		w.WriteHeader(status)
+		w.Write([]byte(`{"message":"la referencia ya no admite devoluciones"}`))
+	}))
+	defer srv.Close()
+	merchant := &domain.Merchant{Name: "CFE", IntegrationURL: srv.URL}
+	tx := &domain.Transaction{ID: uuid.New(), Amount: domain.MustParseMoney("20"), Currency: "MXN"}
+	refund := &domain.Refund{ID: uuid.New(), Amount: domain.MustParseMoney("20")}
+	c := NewHTTPConnector(time.Second)
+
+	assert.NoError(t, c.NotifyRefund(merchant, tx, refund))
+
+	status = http.StatusUnprocessableEntity
+	err := c.NotifyRefund(merchant, tx, refund)
+	assert.ErrorIs(t, err, domain.ErrRefundRejected)
+	assert.Contains(t, err.Error(), "ya no admite devoluciones")
+
+	// Sin respuesta definitiva es que el biller no está disponible
+	status = http.StatusBadGateway
+	assert.ErrorIs(t, c.NotifyRefund(merchant, tx, refund), domain.ErrMerchantUnavailable)
+}
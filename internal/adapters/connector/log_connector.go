@@ -0,0 +1,55 @@
+package connector
+
+import (
+	"hash/fnv"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/scorazag/gopayhub/internal/core/domain"
+)
+
+// stubCustomers son los titulares que inventa el stub de consulta de recibos
+var stubCustomers = []string{"María González", "José Hernández", "Guadalupe López", "Juan Martínez", "Ana Pérez"}
+
+// LogConnector implementa ports.MerchantConnector solo dejando constancia en el log.
+// Sirve mientras los billers no tengan integración real.
+type LogConnector struct{}
+
+func NewLogConnector() *LogConnector {
+	return &LogConnector{}
+}
+
+// NotifyPayment aprueba siempre; el folio es el ID de la transacción
+func (c *LogConnector) NotifyPayment(merchant *domain.Merchant, tx *domain.Transaction) (*domain.PaymentConfirmation, error) {
+	log.Printf("[connector] pago %s de %s %s a %s (referencia %s)", tx.ID, tx.Amount, tx.Currency, merchant.Name, tx.Reference)
+	return &domain.PaymentConfirmation{Approved: true, AuthorizationNumber: tx.ID.String()}, nil
+}
+
+func (c *LogConnector) NotifyRefund(merchant *domain.Merchant, tx *domain.Transaction, refund *domain.Refund) error {
+	log.Printf("[connector] devolución %s de %s a %s (transacción %s)", refund.ID, refund.Amount, merchant.Name, tx.ID)
+	return nil
+}
+
+// InquireBill inventa un recibo a partir de la referencia: la misma referencia siempre da el mismo
+// titular y monto (entre $100 y $2,099.99) con vencimiento en 10 días. Las referencias que terminan
+// en "000" no tienen recibo pendiente, para poder probar ese caso.
+func (c *LogConnector) InquireBill(merchant *domain.Merchant, reference string) (*domain.Bill, error) {
+	log.Printf("[connector] consulta de recibo %s a %s", reference, merchant.Name)
+	if strings.HasSuffix(reference, "000") {
+		return nil, domain.ErrBillNotFound
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(reference))
+	sum := h.Sum32()
+
+	y, m, d := time.Now().AddDate(0, 0, 10).Date()
+	dueDate := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	return &domain.Bill{
+		CustomerName: stubCustomers[sum%uint32(len(stubCustomers))],
+		AmountDue:    domain.MoneyFromUnits(100) + domain.Money(sum%200000),
+		Currency:     merchant.Currency,
+		DueDate:      &dueDate,
+	}, nil
+}
@@ -0,0 +1,224 @@
+package connector
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/scorazag/gopayhub/internal/core/ports"
+)
+
+// errCircuitOpen es lo que regresa una llamada que no se hizo porque el circuito del biller está abierto;
+// siempre va junto con domain.ErrMerchantUnavailable para que el cliente reciba un 503 y no un 500
+var errCircuitOpen = errors.New("circuito abierto")
+
+// ResilienceConfig es cómo se protege la llamada a cada biller
+type ResilienceConfig struct {
+	FailureThreshold int           // Fallas seguidas que abren el circuito
+	OpenFor          time.Duration // Cuánto se queda abierto antes de dejar pasar una llamada de prueba
+	MaxAttempts      int           // Intentos de las consultas de recibos; 1 = sin reintentos
+	Backoff          time.Duration // Espera antes del primer reintento; se duplica en cada uno y lleva jitter
+}
+
+// ResilientConnector envuelve a otro MerchantConnector con un circuit breaker por biller:
+// después de FailureThreshold fallas seguidas se le deja de llamar durante OpenFor y las
+// llamadas fallan de inmediato. Solo las consultas de recibos se reintentan; los pagos y las
+// devoluciones no son seguros de repetir en la misma petición (los pagos asíncronos los reintenta la cola).
+// Una respuesta del biller, aunque sea un rechazo o un recibo inexistente, no cuenta como falla.
+type ResilientConnector struct {
+	next   ports.MerchantConnector
+	config ResilienceConfig
+	now    func() time.Time
+	sleep  func(time.Duration)
+
+	mu       sync.Mutex
+	circuits map[uint]*circuit
+}
+
+// circuit es el estado del breaker de un biller; se modifica con ResilientConnector.mu tomado
+type circuit struct {
+	name      string
+	state     string
+	failures  int
+	lastError string
+	openedAt  time.Time
+	retryAt   time.Time
+	probing   bool // Hay una llamada de prueba en curso (HALF_OPEN)
+}
+
+func NewResilientConnector(next ports.MerchantConnector, config ResilienceConfig) *ResilientConnector {
+	if config.FailureThreshold < 1 {
+		config.FailureThreshold = 1
+	}
+	if config.MaxAttempts < 1 {
+		config.MaxAttempts = 1
+	}
+	return &ResilientConnector{next: next, config: config, now: time.Now, sleep: time.Sleep, circuits: map[uint]*circuit{}}
+}
+
+func (c *ResilientConnector) NotifyPayment(merchant *domain.Merchant, tx *domain.Transaction) (*domain.PaymentConfirmation, error) {
+	var confirmation *domain.PaymentConfirmation
+	err := c.call(merchant, func() (err error) {
+		confirmation, err = c.next.NotifyPayment(merchant, tx)
+		return err
+	})
+	return confirmation, err
+}
+
+func (c *ResilientConnector) NotifyRefund(merchant *domain.Merchant, tx *domain.Transaction, refund *domain.Refund) error {
+	return c.call(merchant, func() error {
+		return c.next.NotifyRefund(merchant, tx, refund)
+	})
+}
+
+// InquireBill es de solo lectura: si el biller no contesta se reintenta hasta MaxAttempts veces
+func (c *ResilientConnector) InquireBill(merchant *domain.Merchant, reference string) (*domain.Bill, error) {
+	var bill *domain.Bill
+	for attempt := 1; ; attempt++ {
+		err := c.call(merchant, func() (err error) {
+			bill, err = c.next.InquireBill(merchant, reference)
+			return err
+		})
+		if err == nil || attempt >= c.config.MaxAttempts || answered(err) || errors.Is(err, errCircuitOpen) {
+			return bill, err
+		}
+		c.sleep(c.backoff(attempt))
+	}
+}
+
+// Available implementa ports.MerchantCircuits: rechaza mientras el circuito esté abierto o
+// mientras se esté probando si el biller ya responde
+func (c *ResilientConnector) Available(merchant *domain.Merchant) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cb, ok := c.circuits[merchant.ID]
+	if !ok {
+		return nil
+	}
+	now := c.now()
+	switch {
+	case cb.state == domain.CircuitOpen && now.Before(cb.retryAt):
+		return fmt.Errorf("%w: %s falló %d veces seguidas; se vuelve a intentar en %s", domain.ErrMerchantUnavailable, merchant.Name, cb.failures, cb.retryAt.Sub(now).Round(time.Second))
+	case cb.state == domain.CircuitHalfOpen && cb.probing:
+		return fmt.Errorf("%w: se está probando si %s ya responde", domain.ErrMerchantUnavailable, merchant.Name)
+	}
+	return nil
+}
+
+// Circuits implementa ports.MerchantCircuits; regresa los billers ordenados por ID
+func (c *ResilientConnector) Circuits() []domain.CircuitState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	states := make([]domain.CircuitState, 0, len(c.circuits))
+	for id, cb := range c.circuits {
+		state := domain.CircuitState{MerchantID: id, MerchantName: cb.name, State: cb.state, ConsecutiveFailures: cb.failures, LastError: cb.lastError}
+		if cb.state != domain.CircuitClosed {
+			openedAt, retryAt := cb.openedAt, cb.retryAt
+			state.OpenedAt, state.RetryAt = &openedAt, &retryAt
+		}
+		// La siguiente llamada ya sería la de prueba
+		if cb.state == domain.CircuitOpen && !now.Before(cb.retryAt) {
+			state.State = domain.CircuitHalfOpen
+		}
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].MerchantID < states[j].MerchantID })
+	return states
+}
+
+// call hace la llamada si el circuito lo permite y registra cómo le fue
+func (c *ResilientConnector) call(merchant *domain.Merchant, fn func() error) error {
+	if err := c.allow(merchant); err != nil {
+		return err
+	}
+	err := fn()
+	c.record(merchant, err)
+	return err
+}
+
+func (c *ResilientConnector) allow(merchant *domain.Merchant) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cb, ok := c.circuits[merchant.ID]
+	if !ok {
+		cb = &circuit{state: domain.CircuitClosed}
+		c.circuits[merchant.ID] = cb
+	}
+	cb.name = merchant.Name
+
+	switch cb.state {
+	case domain.CircuitOpen:
+		if c.now().Before(cb.retryAt) {
+			return fmt.Errorf("%w: %s: %w hasta las %s", domain.ErrMerchantUnavailable, merchant.Name, errCircuitOpen, cb.retryAt.Format(time.TimeOnly))
+		}
+		// Ya pasó el tiempo: esta llamada es la prueba y las demás esperan su resultado
+		cb.state = domain.CircuitHalfOpen
+		cb.probing = true
+	case domain.CircuitHalfOpen:
+		if cb.probing {
+			return fmt.Errorf("%w: %s: %w mientras se prueba si ya responde", domain.ErrMerchantUnavailable, merchant.Name, errCircuitOpen)
+		}
+		cb.probing = true
+	}
+	return nil
+}
+
+func (c *ResilientConnector) record(merchant *domain.Merchant, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cb := c.circuits[merchant.ID]
+	if err == nil || answered(err) {
+		cb.state, cb.failures, cb.lastError, cb.probing = domain.CircuitClosed, 0, "", false
+		return
+	}
+
+	cb.failures++
+	cb.lastError = failureSummary(err)
+	if cb.state == domain.CircuitHalfOpen || cb.failures >= c.config.FailureThreshold {
+		now := c.now()
+		cb.state, cb.openedAt, cb.retryAt, cb.probing = domain.CircuitOpen, now, now.Add(c.config.OpenFor), false
+	}
+}
+
+// backoff es la espera antes del reintento attempt: la mitad fija y la otra mitad al azar,
+// para que las consultas que fallaron juntas no vuelvan a llegar juntas
+func (c *ResilientConnector) backoff(attempt int) time.Duration {
+	d := c.config.Backoff << (attempt - 1)
+	if d <= 1 {
+		return d
+	}
+	return d/2 + rand.N(d/2)
+}
+
+// answered dice si err es una respuesta del biller (por ejemplo domain.ErrBillNotFound) y no una falla
+func answered(err error) bool {
+	var coded domain.CodedError
+	return errors.As(err, &coded) && !errors.Is(err, domain.ErrMerchantUnavailable)
+}
+
+// failureSummary describe la falla para el estado del circuito sin el texto del error,
+// que puede traer el IntegrationURL del biller (con su host y a veces credenciales)
+func failureSummary(err error) string {
+	var status *statusError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &status):
+		return fmt.Sprintf("HTTP %d", status.status)
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.As(err, &netErr):
+		return "sin conexión"
+	default:
+		return "respuesta inválida"
+	}
+}
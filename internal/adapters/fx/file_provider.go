@@ -0,0 +1,59 @@
+package fx
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/scorazag/gopayhub/internal/core/domain"
+)
+
+// rateFile es el formato del archivo de tipos de cambio:
+//
+//	{"source": "banxico-fix", "as_of": "2026-10-16T12:00:00-06:00", "rates": {"USD/MXN": "17.0512"}}
+type rateFile struct {
+	Source string            `json:"source"`
+	AsOf   time.Time         `json:"as_of"`
+	Rates  map[string]string `json:"rates"`
+}
+
+// FileProvider implementa ports.FXRateProvider leyendo tipos de cambio fijos de un archivo JSON.
+// Sirve como stub mientras no tengamos un proveedor en línea.
+type FileProvider struct {
+	file rateFile
+}
+
+func NewFileProvider(path string) (*FileProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file rateFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("archivo de tipos de cambio inválido: %w", err)
+	}
+	return &FileProvider{file: file}, nil
+}
+
+// GetRate busca el par directo (USD/MXN) y si no existe usa el inverso del par contrario (MXN/USD)
+func (p *FileProvider) GetRate(from, to string) (*domain.FXQuote, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	quote := &domain.FXQuote{From: from, To: to, Source: p.file.Source, AsOf: p.file.AsOf}
+
+	if rate, ok := p.file.Rates[from+"/"+to]; ok {
+		quote.Rate = rate
+		return quote, nil
+	}
+	if rate, ok := p.file.Rates[to+"/"+from]; ok {
+		r, ok := new(big.Rat).SetString(rate)
+		if !ok || r.Sign() <= 0 {
+			return nil, fmt.Errorf("tipo de cambio inválido %s/%s: %q", to, from, rate)
+		}
+		quote.Rate = new(big.Rat).Inv(r).FloatString(10)
+		return quote, nil
+	}
+	return nil, fmt.Errorf("no hay tipo de cambio para %s/%s", from, to)
+}
@@ -0,0 +1,82 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/scorazag/gopayhub/internal/core/ports"
+)
+
+// AccountHandler expone el saldo y el estado de cuenta del cliente autenticado
+type AccountHandler struct {
+	service  ports.AccountService
+	location *time.Location // Zona horaria del negocio para los filtros por día
+}
+
+func NewAccountHandler(service ports.AccountService, location *time.Location) *AccountHandler {
+	return &AccountHandler{service: service, location: location}
+}
+
+func (h *AccountHandler) GetBalance(c *gin.Context) {
+	clientID, ok := authenticatedClientID(c)
+	if !ok {
+		return
+	}
+
+	balance, err := h.service.GetBalance(clientID, c.Query("currency"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, balance)
+}
+
+// GetStatement acepta currency, from, to (mismos formatos que los listados), limit y cursor
+func (h *AccountHandler) GetStatement(c *gin.Context) {
+	clientID, ok := authenticatedClientID(c)
+	if !ok {
+		return
+	}
+
+	q, err := parseStatementQuery(c, h.location)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	q.ClientID = clientID
+
+	statement, err := h.service.GetStatement(q)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statement)
+}
+
+func parseStatementQuery(c *gin.Context, loc *time.Location) (domain.StatementQuery, error) {
+	q := domain.StatementQuery{Currency: c.Query("currency")}
+	var err error
+	if q.From, err = parseTimeParam(c, "from", loc); err != nil {
+		return q, err
+	}
+	if q.To, err = parseTimeParam(c, "to", loc); err != nil {
+		return q, err
+	}
+	if v := c.Query("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil || q.Limit < 1 {
+			return q, fmt.Errorf("%w: limit inválido", domain.ErrInvalidFilter)
+		}
+	}
+	if v := c.Query("cursor"); v != "" {
+		if q.After, err = domain.DecodePageCursor(v); err != nil {
+			return q, err
+		}
+	}
+	return q, nil
+}
@@ -0,0 +1,87 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/scorazag/gopayhub/internal/core/ports"
+)
+
+// AuthorizationRequest es el body de POST /authorizations: como el de un pago, pero sin consulta del recibo
+type AuthorizationRequest struct {
+	Amount     domain.Money `json:"amount" binding:"required"`
+	Currency   string       `json:"currency"` // ISO 4217; vacío = MXN
+	MerchantID uint         `json:"merchant_id" binding:"required"`
+	Reference  string       `json:"reference" binding:"required"`
+}
+
+type AuthorizationHandler struct {
+	service ports.AuthorizationService
+}
+
+func NewAuthorizationHandler(service ports.AuthorizationService) *AuthorizationHandler {
+	return &AuthorizationHandler{service: service}
+}
+
+// Authorize retiene el monto de un pago
+func (h *AuthorizationHandler) Authorize(c *gin.Context) {
+	var req AuthorizationRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondInvalidRequest(c, "Datos inválidos: "+err.Error())
+		return
+	}
+
+	clientID, ok := authenticatedClientID(c)
+	if !ok {
+		return
+	}
+
+	idemKey := c.GetHeader("X-Idempotency-Key")
+
+	tx, err := h.service.AuthorizePayment(req.Amount, req.Currency, req.MerchantID, clientID, req.Reference, idemKey)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, tx)
+}
+
+func (h *AuthorizationHandler) Capture(c *gin.Context) {
+	h.settle(c, h.service.CapturePayment)
+}
+
+func (h *AuthorizationHandler) Release(c *gin.Context) {
+	h.settle(c, h.service.ReleasePayment)
+}
+
+// settle resuelve una retención existente (capturar o liberar)
+func (h *AuthorizationHandler) settle(c *gin.Context, action func(uuid.UUID, uint) (*domain.Transaction, error)) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondInvalidRequest(c, "ID de transacción inválido")
+		return
+	}
+
+	clientID, ok := authenticatedClientID(c)
+	if !ok {
+		return
+	}
+
+	tx, err := action(id, clientID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// Capturado pero el biller no contestó: lo termina la cola, como un pago directo
+	if tx.Status == "PENDING" {
+		c.Header("Location", "/api/v1/transactions/"+tx.ID.String())
+		c.JSON(http.StatusAccepted, tx)
+		return
+	}
+	c.JSON(http.StatusOK, tx)
+}
@@ -0,0 +1,39 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/scorazag/gopayhub/internal/core/ports"
+)
+
+// BillHandler expone la consulta del recibo que hace la cajera antes de cobrar
+type BillHandler struct {
+	service ports.BillService
+}
+
+func NewBillHandler(service ports.BillService) *BillHandler {
+	return &BillHandler{service: service}
+}
+
+// InquireBill atiende GET /merchants/:id/bills?reference=...
+func (h *BillHandler) InquireBill(c *gin.Context) {
+	merchantID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondInvalidRequest(c, "ID de merchant inválido")
+		return
+	}
+
+	clientID, ok := authenticatedClientID(c)
+	if !ok {
+		return
+	}
+
+	bill, err := h.service.InquireBill(uint(merchantID), clientID, c.Query("reference"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, bill)
+}
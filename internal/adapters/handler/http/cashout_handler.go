@@ -4,13 +4,15 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/scorazag/gopayhub/internal/core/domain"
 	"github.com/scorazag/gopayhub/internal/core/ports"
 )
 
 type CashOutRequest struct {
-	Amount    float64 `json:"amount" binding:"required,gt=0"`
-	Reference string  `json:"reference" binding:"required"`
-	StoreName string  `json:"store_name"`
+	Amount    domain.Money `json:"amount" binding:"required,gt=0"`
+	Currency  string       `json:"currency"` // ISO 4217; vacío = MXN
+	Reference string       `json:"reference" binding:"required"`
+	StoreName string       `json:"store_name"`
 }
 
 type CashOutHandler struct {
@@ -25,24 +27,23 @@ func (h *CashOutHandler) ProcessCashOut(c *gin.Context) {
 	var req CashOutRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos: " + err.Error()})
+		respondInvalidRequest(c, "Datos inválidos: "+err.Error())
 		return
 	}
 
 	// Recuperamos el client_id del middleware de autenticación
-	clientID, exists := c.Get("client_id")
-	if !exists {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo identificar al cliente"})
+	clientID, ok := authenticatedClientID(c)
+	if !ok {
 		return
 	}
 
 	idemKey := c.GetHeader("X-Idempotency-Key")
 
 	// Ejecutamos el retiro
-	res, err := h.service.ProcessCashOut(req.Amount, 0, clientID.(uint), req.Reference, idemKey)
+	res, err := h.service.ProcessCashOut(req.Amount, req.Currency, 0, clientID, req.Reference, req.StoreName, idemKey)
 	if err != nil {
-		// Si el error es "insufficient funds", regresamos un 422 (Unprocessable Entity)
-		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		// Si el error es "insufficient funds" o un límite, regresamos un 422 (Unprocessable Entity)
+		respondError(c, err)
 		return
 	}
 
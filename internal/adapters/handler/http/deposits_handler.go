@@ -4,13 +4,15 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/scorazag/gopayhub/internal/core/domain"
 	"github.com/scorazag/gopayhub/internal/core/ports"
 )
 
 type DepositRequest struct {
-	Amount    float64 `json:"amount" binding:"required,gt=0"`
-	Reference string  `json:"reference" binding:"required"`
-	StoreName string  `json:"store_name"` // Opcional: El nombre del punto de venta
+	Amount    domain.Money `json:"amount" binding:"required,gt=0"`
+	Currency  string       `json:"currency"` // ISO 4217; vacío = MXN
+	Reference string       `json:"reference" binding:"required"`
+	StoreName string       `json:"store_name"` // Opcional: El nombre del punto de venta
 }
 
 type DepositHandler struct {
@@ -25,23 +27,22 @@ func (h *DepositHandler) ProcessDeposit(c *gin.Context) {
 	var req DepositRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos: " + err.Error()})
+		respondInvalidRequest(c, "Datos inválidos: "+err.Error())
 		return
 	}
 
 	// Obtenemos el client_id del Middleware de Auth
-	clientID, exists := c.Get("client_id")
-	if !exists {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo identificar al cliente"})
+	clientID, ok := authenticatedClientID(c)
+	if !ok {
 		return
 	}
 
 	idemKey := c.GetHeader("X-Idempotency-Key")
 
 	// Llamamos al servicio (aquí pasamos 0 o un valor por defecto para merchantID si no aplica)
-	res, err := h.service.ProcessDeposit(req.Amount, 0, clientID.(uint), req.Reference, idemKey)
+	res, err := h.service.ProcessDeposit(req.Amount, req.Currency, 0, clientID, req.Reference, req.StoreName, idemKey)
 	if err != nil {
-		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
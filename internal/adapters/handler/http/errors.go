@@ -0,0 +1,85 @@
+package http
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/scorazag/gopayhub/internal/adapters/handler/http/middleware"
+	"github.com/scorazag/gopayhub/internal/core/domain"
+)
+
+// statusByCode dice con qué status sale cada código del core.
+// Los códigos que no están aquí son reglas de negocio y salen con 422.
+var statusByCode = map[string]int{
+	// La petición está mal formada
+	domain.ErrInvalidAmount.Code:       http.StatusBadRequest,
+	domain.ErrInvalidMoney.Code:        http.StatusBadRequest,
+	domain.ErrInvalidCurrency.Code:     http.StatusBadRequest,
+	domain.ErrUnsupportedCurrency.Code: http.StatusBadRequest,
+	domain.ErrInvalidCursor.Code:       http.StatusBadRequest,
+	domain.ErrInvalidFilter.Code:       http.StatusBadRequest,
+	domain.ErrVoidDetailsRequired.Code: http.StatusBadRequest,
+	domain.ErrReferenceRequired.Code:   http.StatusBadRequest,
+	domain.ErrInvalidReference.Code:    http.StatusBadRequest,
+
+	// No existe (o es de otro cliente)
+	domain.ErrNotFound.Code:            http.StatusNotFound,
+	domain.ErrMerchantNotFound.Code:    http.StatusNotFound,
+	domain.ErrTransactionNotFound.Code: http.StatusNotFound,
+	domain.ErrDepositNotFound.Code:     http.StatusNotFound,
+	domain.ErrCashOutNotFound.Code:     http.StatusNotFound,
+	domain.ErrBillNotFound.Code:        http.StatusNotFound,
+
+	// La operación no está en un estado que lo permita
+	domain.ErrNotRefundable.Code: http.StatusConflict,
+	domain.ErrNoActiveHold.Code:  http.StatusConflict,
+	domain.ErrHoldExpired.Code:   http.StatusConflict,
+	domain.ErrAlreadyVoided.Code: http.StatusConflict,
+	domain.ErrNotVoidable.Code:   http.StatusConflict,
+
+	// Depende de un tercero que no respondió; se puede reintentar
+	domain.ErrFXRateUnavailable.Code:   http.StatusServiceUnavailable,
+	domain.ErrMerchantUnavailable.Code: http.StatusServiceUnavailable,
+}
+
+// respondError es el único lugar donde un error del core se vuelve respuesta HTTP.
+// Un error sin código es una falla nuestra (BD, red): sale como 500 sin detalles y el detalle va al log.
+func respondError(c *gin.Context, err error) {
+	var coded domain.CodedError
+	if !errors.As(err, &coded) {
+		log.Printf("[http] %s %s request_id=%s: %v", c.Request.Method, c.FullPath(), c.GetString(middleware.RequestIDKey), err)
+		middleware.AbortWithError(c, http.StatusInternalServerError, middleware.ErrorResponse{
+			Code:    middleware.ErrCodeInternal,
+			Message: "Error interno; intente más tarde",
+		})
+		return
+	}
+
+	status, ok := statusByCode[coded.ErrorCode()]
+	if !ok {
+		status = http.StatusUnprocessableEntity
+	}
+	res := middleware.ErrorResponse{Code: coded.ErrorCode(), Message: err.Error()}
+	var limitErr *domain.LimitExceededError
+	if errors.As(err, &limitErr) {
+		res.Details = map[string]any{"limit": limitErr.Limit.Code}
+	}
+	middleware.AbortWithError(c, status, res)
+}
+
+// respondInvalidRequest es para lo que el handler rechaza antes de llegar al core (JSON mal formado, ID inválido)
+func respondInvalidRequest(c *gin.Context, message string) {
+	middleware.AbortWithError(c, http.StatusBadRequest, middleware.ErrorResponse{Code: middleware.ErrCodeInvalidRequest, Message: message})
+}
+
+// authenticatedClientID lee el cliente que dejó AuthMiddleware; si no está ya respondió
+func authenticatedClientID(c *gin.Context) (uint, bool) {
+	id, exists := c.Get("client_id")
+	if !exists {
+		respondError(c, errors.New("no se pudo identificar al cliente"))
+		return 0, false
+	}
+	return id.(uint), true
+}
@@ -0,0 +1,74 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/scorazag/gopayhub/internal/adapters/handler/http/middleware"
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func serveError(err error) (*httptest.ResponseRecorder, middleware.ErrorResponse) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.RequestID())
+	r.POST("/transactions", func(c *gin.Context) { respondError(c, err) })
+
+	req := httptest.NewRequest(http.MethodPost, "/transactions", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-123")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var res middleware.ErrorResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &res)
+	return w, res
+}
+
+func TestRespondError_MapsCodesToStatus(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{domain.ErrInsufficientFunds, http.StatusUnprocessableEntity, "INSUFFICIENT_FUNDS"},
+		{domain.ErrMerchantNotFound, http.StatusNotFound, "MERCHANT_NOT_FOUND"},
+		{fmt.Errorf("%w: \"XYZ\" no es un código ISO 4217", domain.ErrInvalidCurrency), http.StatusBadRequest, "INVALID_CURRENCY"},
+		{domain.ErrHoldExpired, http.StatusConflict, "HOLD_EXPIRED"},
+		{domain.ErrFXRateUnavailable, http.StatusServiceUnavailable, "FX_RATE_UNAVAILABLE"},
+	}
+	for _, tc := range cases {
+		w, res := serveError(tc.err)
+
+		assert.Equal(t, tc.status, w.Code, tc.code)
+		assert.Equal(t, tc.code, res.Code)
+		assert.Equal(t, tc.err.Error(), res.Message)
+		assert.Equal(t, "req-123", res.RequestID)
+	}
+}
+
+func TestRespondError_LimitIncludesWhichLimit(t *testing.T) {
+	err := &domain.LimitExceededError{Limit: domain.Limit{Code: "CASH_DEPOSIT_MAX", Period: domain.LimitPerOperation}}
+
+	w, res := serveError(err)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, "PER_OPERATION_LIMIT_EXCEEDED", res.Code)
+	assert.Equal(t, "CASH_DEPOSIT_MAX", res.Details["limit"])
+	assert.ErrorIs(t, err, domain.ErrLimitExceeded)
+}
+
+func TestRespondError_UncodedErrorIsInternal(t *testing.T) {
+	w, res := serveError(errors.New("dial tcp 10.0.0.5:5432: connection refused"))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, middleware.ErrCodeInternal, res.Code)
+	// El detalle de la falla se queda en el log, no se le muestra al cliente
+	assert.NotContains(t, res.Message, "10.0.0.5")
+	assert.Equal(t, "req-123", res.RequestID)
+}
@@ -0,0 +1,141 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/scorazag/gopayhub/internal/core/domain"
+)
+
+// Los listados usan los mismos filtros; merchant_id solo aplica a transacciones y store_name a depósitos y retiros.
+// Las fechas van en RFC3339 (2024-05-01T00:00:00-06:00) o como día (2024-05-01, a medianoche en la zona
+// horaria del negocio, BUSINESS_TIMEZONE, que es la que usan los límites diarios y la ventana de anulación).
+
+func (h *QueryHandler) ListTransactions(c *gin.Context) {
+	f, ok := bindListFilter(c, h.location, true, false)
+	if !ok {
+		return
+	}
+	page, err := h.service.ListTransactions(f)
+	respondList(c, page, err)
+}
+
+func (h *QueryHandler) ListDeposits(c *gin.Context) {
+	f, ok := bindListFilter(c, h.location, false, true)
+	if !ok {
+		return
+	}
+	page, err := h.service.ListDeposits(f)
+	respondList(c, page, err)
+}
+
+func (h *QueryHandler) ListCashOuts(c *gin.Context) {
+	f, ok := bindListFilter(c, h.location, false, true)
+	if !ok {
+		return
+	}
+	page, err := h.service.ListCashOuts(f)
+	respondList(c, page, err)
+}
+
+// bindListFilter arma el filtro con el query string y el cliente autenticado; si algo falla ya respondió
+func bindListFilter(c *gin.Context, loc *time.Location, byMerchant, byStore bool) (domain.OperationFilter, bool) {
+	f, err := parseListFilter(c, loc, byMerchant, byStore)
+	if err != nil {
+		respondError(c, err)
+		return f, false
+	}
+	clientID, ok := authenticatedClientID(c)
+	if !ok {
+		return f, false
+	}
+	f.ClientID = clientID
+	return f, true
+}
+
+func parseListFilter(c *gin.Context, loc *time.Location, byMerchant, byStore bool) (domain.OperationFilter, error) {
+	var f domain.OperationFilter
+	var err error
+
+	if f.From, err = parseTimeParam(c, "from", loc); err != nil {
+		return f, err
+	}
+	if f.To, err = parseTimeParam(c, "to", loc); err != nil {
+		return f, err
+	}
+	if f.MinAmount, err = parseMoneyParam(c, "min_amount"); err != nil {
+		return f, err
+	}
+	if f.MaxAmount, err = parseMoneyParam(c, "max_amount"); err != nil {
+		return f, err
+	}
+	f.Status = c.Query("status")
+	f.Reference = c.Query("reference")
+
+	if v := c.Query("merchant_id"); v != "" {
+		if !byMerchant {
+			return f, fmt.Errorf("%w: merchant_id no aplica a este listado", domain.ErrInvalidFilter)
+		}
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return f, fmt.Errorf("%w: merchant_id inválido", domain.ErrInvalidFilter)
+		}
+		merchantID := uint(id)
+		f.MerchantID = &merchantID
+	}
+	if v := c.Query("store_name"); v != "" {
+		if !byStore {
+			return f, fmt.Errorf("%w: store_name no aplica a este listado", domain.ErrInvalidFilter)
+		}
+		f.StoreName = v
+	}
+	if v := c.Query("limit"); v != "" {
+		if f.Limit, err = strconv.Atoi(v); err != nil || f.Limit < 1 {
+			return f, fmt.Errorf("%w: limit inválido", domain.ErrInvalidFilter)
+		}
+	}
+	if v := c.Query("cursor"); v != "" {
+		if f.After, err = domain.DecodePageCursor(v); err != nil {
+			return f, err
+		}
+	}
+	return f, nil
+}
+
+// parseTimeParam lee una fecha del query string; un día sin hora es su medianoche en loc
+func parseTimeParam(c *gin.Context, name string, loc *time.Location) (*time.Time, error) {
+	v := c.Query(name)
+	if v == "" {
+		return nil, nil
+	}
+	// RFC3339 trae su propio desfase, así que loc solo aplica al día sin hora
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.ParseInLocation(layout, v, loc); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s debe ser una fecha RFC3339 o YYYY-MM-DD", domain.ErrInvalidFilter, name)
+}
+
+func parseMoneyParam(c *gin.Context, name string) (*domain.Money, error) {
+	v := c.Query(name)
+	if v == "" {
+		return nil, nil
+	}
+	m, err := domain.ParseMoney(v)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", domain.ErrInvalidFilter, name, err)
+	}
+	return &m, nil
+}
+
+func respondList(c *gin.Context, page any, err error) {
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
@@ -0,0 +1,28 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseListFilter_DatesInBusinessTimezone(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	loc, err := time.LoadLocation("America/Mexico_City")
+	require.NoError(t, err)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/transactions?from=2024-05-01&to=2024-05-02T00:00:00Z", nil)
+
+	f, err := parseListFilter(c, loc, true, false)
+
+	require.NoError(t, err)
+	// El día sin hora empieza a medianoche en la Ciudad de México (06:00 UTC), no a medianoche UTC
+	assert.True(t, f.From.Equal(time.Date(2024, 5, 1, 6, 0, 0, 0, time.UTC)), f.From)
+	// Con desfase explícito se respeta el que trae
+	assert.True(t, f.To.Equal(time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)), f.To)
+}
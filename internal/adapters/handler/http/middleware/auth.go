@@ -12,16 +12,14 @@ func AuthMiddleware(repo *postgres.PaymentRepository) gin.HandlerFunc {
 		// 1. Extraer la key del header X-API-KEY
 		apiKey := c.GetHeader("X-API-KEY")
 		if apiKey == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Se requiere API Key"})
-			c.Abort()
+			AbortWithError(c, http.StatusUnauthorized, ErrorResponse{Code: ErrCodeAPIKeyRequired, Message: "Se requiere API Key"})
 			return
 		}
 
 		// 2. Validar contra la base de datos
 		client, err := repo.GetClientByApiKey(apiKey)
 		if err != nil {
-			c.JSON(http.StatusForbidden, gin.H{"error": "API Key inválida o cliente inactivo"})
-			c.Abort()
+			AbortWithError(c, http.StatusForbidden, ErrorResponse{Code: ErrCodeInvalidAPIKey, Message: "API Key inválida o cliente inactivo"})
 			return
 		}
 
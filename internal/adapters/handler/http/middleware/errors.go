@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// Códigos de error que no vienen del core
+const (
+	ErrCodeInvalidRequest = "INVALID_REQUEST" // El cuerpo o los parámetros no se pudieron leer
+	ErrCodeInternal       = "INTERNAL_ERROR"  // Falla nuestra; el detalle queda en el log con el request_id
+	ErrCodeAPIKeyRequired = "API_KEY_REQUIRED"
+	ErrCodeInvalidAPIKey  = "INVALID_API_KEY"
+	ErrCodeRouteNotFound  = "ROUTE_NOT_FOUND"
+)
+
+// ErrorResponse es el cuerpo de todas las respuestas de error de la API.
+// Los clientes deciden con Code; Message es para personas y puede cambiar.
+type ErrorResponse struct {
+	Code      string         `json:"code"`
+	Message   string         `json:"message"`
+	RequestID string         `json:"request_id"`
+	Details   map[string]any `json:"details,omitempty"` // Ej: {"limit": "CASH_DEPOSIT_MAX"}
+}
+
+// AbortWithError responde el error con el request_id de la petición y detiene la cadena de handlers
+func AbortWithError(c *gin.Context, status int, res ErrorResponse) {
+	res.RequestID = c.GetString(RequestIDKey)
+	c.AbortWithStatusJSON(status, res)
+}
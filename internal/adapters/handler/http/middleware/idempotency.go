@@ -0,0 +1,233 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/scorazag/gopayhub/internal/core/ports"
+)
+
+// IdempotencyHeader es el header con el que el cliente identifica un intento (se repite en cada reintento)
+const IdempotencyHeader = "X-Idempotency-Key"
+
+// ReplayedHeader marca las respuestas que se repiten de una petición anterior
+const ReplayedHeader = "Idempotent-Replayed"
+
+// Códigos de error de idempotencia
+const (
+	ErrCodeIdempotencyMismatch   = "IDEMPOTENCY_KEY_REUSED"          // La llave ya se usó con otro cuerpo o en otra ruta
+	ErrCodeIdempotencyInProgress = "IDEMPOTENCY_REQUEST_IN_PROGRESS" // Otra petición con la misma llave no ha terminado
+)
+
+// requestHash es el SHA-256 de método, ruta y cuerpo en forma canónica: el orden de los campos
+// y los espacios del JSON no cambian el hash, pero cualquier valor distinto sí.
+func requestHash(method, path string, body []byte) string {
+	canonical := body
+	var parsed any
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber() // Conserva los números tal cual llegaron: 100.5 no se vuelve 100.50000001
+	if err := decoder.Decode(&parsed); err == nil {
+		// json.Marshal ordena las llaves de los objetos
+		if b, err := json.Marshal(parsed); err == nil {
+			canonical = b
+		}
+	}
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(canonical)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder copia lo que escribe el handler para poder guardarlo
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency hace que todas las rutas que modifican algo respondan igual a un reintento con la misma llave:
+// si la llave ya se usó regresa la respuesta guardada sin volver a ejecutar el handler.
+// Si la llave llega con otro cuerpo regresa 422 IDEMPOTENCY_KEY_REUSED en lugar de esconder el error del cliente.
+//
+// La llave se aparta (IN_PROGRESS) antes de ejecutar el handler, así que de dos peticiones simultáneas
+// con la misma llave solo una trabaja; la otra recibe 409 IDEMPOTENCY_REQUEST_IN_PROGRESS y puede reintentar.
+// Va después de AuthMiddleware: la llave es de cada cliente y de cada operación (método + ruta), y vence después
+// de retention. Se guarda el resultado completo (status, cuerpo y headers) de los éxitos y de los errores
+// de negocio que no cambian al reintentar (replayedErrorCodes); cualquier otro error libera la llave.
+func Idempotency(repo ports.IdempotencyRepository, retention time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyHeader)
+		if key == "" || c.Request.Method == http.MethodGet {
+			c.Next()
+			return
+		}
+
+		// Leemos el cuerpo para el hash y lo dejamos listo para el handler
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			AbortWithError(c, http.StatusBadRequest, ErrorResponse{Code: ErrCodeInvalidRequest, Message: "No se pudo leer el cuerpo de la petición"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		hash := requestHash(c.Request.Method, c.Request.URL.Path, body)
+
+		operation := c.FullPath()
+		if operation == "" {
+			operation = c.Request.URL.Path
+		}
+		scope := domain.IdempotencyScope{ClientID: c.GetUint("client_id"), Operation: c.Request.Method + " " + operation, Key: key}
+
+		// 1. Apartamos la llave antes de hacer cualquier cosa
+		claim := &domain.IdempotencyKey{
+			IdempotencyScope: scope,
+			RequestHash:      hash,
+			Status:           domain.IdempotencyInProgress,
+			ExpiresAt:        time.Now().Add(retention),
+		}
+		claimed, err := repo.ClaimIdempotencyKey(claim)
+		if err != nil {
+			log.Printf("[idempotency] no se pudo apartar la llave %q: %v", key, err)
+			AbortWithError(c, http.StatusInternalServerError, ErrorResponse{Code: ErrCodeInternal, Message: "No se pudo registrar la llave de idempotencia"})
+			return
+		}
+
+		// 2. Ya existía: es un reintento (o una petición simultánea)
+		if !claimed {
+			replay(c, repo, scope, hash)
+			return
+		}
+
+		// 3. Es nuestra: ejecutamos el handler y guardamos lo que respondió.
+		// Si el handler falla (o hace panic) liberamos la llave para que el cliente pueda reintentar.
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			if err := repo.DeleteIdempotencyKey(scope); err != nil {
+				log.Printf("[idempotency] no se pudo liberar la llave %q: %v", key, err)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		status := recorder.Status()
+		if !replayable(status, recorder.body.Bytes()) {
+			return
+		}
+		headers, _ := json.Marshal(replayedHeaders(recorder.Header()))
+		claim.Status = domain.IdempotencyCompleted
+		claim.ResponseJSON = recorder.body.String()
+		claim.StatusCode = status
+		claim.ResponseHeaders = string(headers)
+		if err := repo.SaveIdempotencyKey(claim); err != nil {
+			// Si la dejamos apartada, cada reintento recibiría 409 para siempre; la liberamos (con el defer)
+			// y el reintento se vuelve a ejecutar: los servicios guardan la llave en la operación para rastrearla
+			log.Printf("[idempotency] ERROR: no se pudo guardar la respuesta de la llave %q, se libera: %v", key, err)
+			return
+		}
+		completed = true
+	}
+}
+
+// replay contesta un reintento con la respuesta guardada, o con el conflicto que corresponda
+func replay(c *gin.Context, repo ports.IdempotencyRepository, scope domain.IdempotencyScope, hash string) {
+	saved, err := repo.GetIdempotencyKey(scope)
+	if err != nil || saved == nil || saved.Key == "" {
+		// La primera petición falló y liberó la llave entre el apartado y esta lectura
+		AbortWithError(c, http.StatusConflict, ErrorResponse{
+			Code:    ErrCodeIdempotencyInProgress,
+			Message: "La petición con esta llave de idempotencia sigue en proceso; reintente en unos segundos",
+		})
+		return
+	}
+	if saved.RequestHash != hash {
+		AbortWithError(c, http.StatusUnprocessableEntity, ErrorResponse{
+			Code:    ErrCodeIdempotencyMismatch,
+			Message: "La llave de idempotencia ya se usó con una petición distinta; use una llave nueva para una operación nueva",
+		})
+		return
+	}
+	if saved.Status == domain.IdempotencyInProgress {
+		AbortWithError(c, http.StatusConflict, ErrorResponse{
+			Code:    ErrCodeIdempotencyInProgress,
+			Message: "La petición con esta llave de idempotencia sigue en proceso; reintente en unos segundos",
+		})
+		return
+	}
+	var headers map[string]string
+	_ = json.Unmarshal([]byte(saved.ResponseHeaders), &headers)
+	contentType := "application/json; charset=utf-8"
+	for name, value := range headers {
+		if name == "Content-Type" {
+			contentType = value
+			continue
+		}
+		c.Header(name, value)
+	}
+	c.Header(ReplayedHeader, "true")
+	c.Data(saved.StatusCode, contentType, []byte(saved.ResponseJSON))
+	c.Abort()
+}
+
+// replayedErrorCodes son los errores que darían lo mismo al reintentar la misma petición: la petición
+// está mal formada, o el biller ya decidió. Los que dependen del estado actual (INSUFFICIENT_FUNDS, límites,
+// lo que resta por devolver, una consulta vencida) no se guardan: el reintento puede salir bien más tarde.
+var replayedErrorCodes = map[string]bool{
+	ErrCodeInvalidRequest:                  true,
+	domain.ErrInvalidAmount.Code:           true,
+	domain.ErrInvalidCurrency.Code:         true,
+	domain.ErrVoidDetailsRequired.Code:     true,
+	domain.ErrReferenceRequired.Code:       true,
+	domain.ErrInvalidReference.Code:        true,
+	domain.ErrReferenceAmountMismatch.Code: true,
+	domain.ErrBillInquiryMismatch.Code:     true,
+	domain.ErrPaymentRejected.Code:         true, // El pago quedó FAILED; repetirlo no lo cambia
+	domain.ErrRefundRejected.Code:          true, // Igual con la devolución
+}
+
+// replayable dice si una respuesta se guarda para repetirla: los éxitos y los errores de replayedErrorCodes.
+// El resto (5xx, 409, 429, errores que dependen del saldo o del día) libera la llave.
+func replayable(status int, body []byte) bool {
+	if status >= 200 && status < 300 {
+		return true
+	}
+	if status != http.StatusBadRequest && status != http.StatusUnprocessableEntity {
+		return false
+	}
+	var res ErrorResponse
+	if err := json.Unmarshal(body, &res); err != nil {
+		return false
+	}
+	return replayedErrorCodes[res.Code]
+}
+
+// replayedHeaders se queda con los headers que son parte del resultado, no de la conexión
+func replayedHeaders(h http.Header) map[string]string {
+	kept := map[string]string{}
+	for _, name := range []string{"Content-Type", "Location", "Preference-Applied"} {
+		if value := h.Get(name); value != "" {
+			kept[name] = value
+		}
+	}
+	return kept
+}
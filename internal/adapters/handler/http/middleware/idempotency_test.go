@@ -0,0 +1,342 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+// memKeys es un ports.IdempotencyRepository en memoria
+type memKeys struct {
+	mu      sync.Mutex
+	keys    map[domain.IdempotencyScope]domain.IdempotencyKey
+	saveErr error // Si no es nil, SaveIdempotencyKey falla
+}
+
+func newMemKeys(saved ...domain.IdempotencyKey) *memKeys {
+	m := &memKeys{keys: map[domain.IdempotencyScope]domain.IdempotencyKey{}}
+	for _, k := range saved {
+		m.keys[k.IdempotencyScope] = k
+	}
+	return m
+}
+
+func (m *memKeys) GetIdempotencyKey(scope domain.IdempotencyScope) (*domain.IdempotencyKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k, ok := m.keys[scope]
+	if !ok {
+		return nil, nil
+	}
+	return &k, nil
+}
+
+func (m *memKeys) SaveIdempotencyKey(key *domain.IdempotencyKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.saveErr != nil {
+		return m.saveErr
+	}
+	m.keys[key.IdempotencyScope] = *key
+	return nil
+}
+
+func (m *memKeys) ClaimIdempotencyKey(key *domain.IdempotencyKey) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if existing, ok := m.keys[key.IdempotencyScope]; ok && existing.ExpiresAt.After(time.Now()) {
+		return false, nil
+	}
+	m.keys[key.IdempotencyScope] = *key
+	return true, nil
+}
+
+func (m *memKeys) DeleteIdempotencyKey(scope domain.IdempotencyScope) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.keys, scope)
+	return nil
+}
+
+func (m *memKeys) PurgeExpiredIdempotencyKeys(now time.Time) (int64, error) {
+	return 0, nil
+}
+
+// fakeAuth hace las veces de AuthMiddleware: el cliente viene en X-Client (1 si no se manda)
+func fakeAuth(c *gin.Context) {
+	clientID, _ := strconv.Atoi(c.GetHeader("X-Client"))
+	if clientID == 0 {
+		clientID = 1
+	}
+	c.Set("client_id", uint(clientID))
+}
+
+// newIdempotentRouter arma un router con una ruta que cuenta cuántas veces se ejecuta
+func newIdempotentRouter(repo *memKeys, status int, calls *int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(fakeAuth, Idempotency(repo, time.Hour))
+	handler := func(c *gin.Context) {
+		*calls++
+		c.JSON(status, gin.H{"path": c.FullPath(), "call": *calls})
+	}
+	r.POST("/transactions", handler)
+	r.POST("/deposits", handler)
+	r.POST("/cashouts", handler)
+	return r
+}
+
+func post(r *gin.Engine, path, key string) *httptest.ResponseRecorder {
+	return postBody(r, path, key, "")
+}
+
+func postBody(r *gin.Engine, path, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(IdempotencyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotency_ReplaysStoredResponse(t *testing.T) {
+	// Una llave ya guardada se contesta sin ejecutar el handler
+	repo := newMemKeys(domain.IdempotencyKey{
+		IdempotencyScope: domain.IdempotencyScope{ClientID: 1, Operation: "POST /transactions", Key: "key-repetida"},
+		RequestHash:      requestHash(http.MethodPost, "/transactions", nil),
+		Status:           domain.IdempotencyCompleted,
+		ResponseJSON:     `{"reference":"PAGO-ANTERIOR"}`,
+		StatusCode:       http.StatusCreated,
+		ExpiresAt:        time.Now().Add(time.Hour),
+	})
+	calls := 0
+	r := newIdempotentRouter(repo, http.StatusCreated, &calls)
+
+	w := post(r, "/transactions", "key-repetida")
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{"reference":"PAGO-ANTERIOR"}`, w.Body.String())
+	assert.Equal(t, "true", w.Header().Get(ReplayedHeader))
+	assert.Equal(t, 0, calls)
+}
+
+func TestIdempotency_EveryOperationRunsOnce(t *testing.T) {
+	for _, path := range []string{"/transactions", "/deposits", "/cashouts"} {
+		repo := newMemKeys()
+		calls := 0
+		r := newIdempotentRouter(repo, http.StatusCreated, &calls)
+
+		first := post(r, path, "retry-1")
+		second := post(r, path, "retry-1")
+
+		assert.Equal(t, 1, calls, path)
+		assert.Equal(t, first.Code, second.Code, path)
+		assert.Equal(t, first.Body.String(), second.Body.String(), path)
+	}
+}
+
+func TestIdempotency_WithoutKeyOrAfterTransientErrorRunsAgain(t *testing.T) {
+	repo := newMemKeys()
+	calls := 0
+	r := newIdempotentRouter(repo, http.StatusCreated, &calls)
+
+	post(r, "/cashouts", "")
+	post(r, "/cashouts", "")
+	assert.Equal(t, 2, calls)
+
+	// Un error pasajero no se guarda: el cliente puede reintentar con la misma llave
+	failing := newIdempotentRouter(repo, http.StatusServiceUnavailable, &calls)
+	post(failing, "/cashouts", "retry-2")
+	assert.Empty(t, repo.keys)
+
+	post(r, "/cashouts", "retry-2")
+	assert.Equal(t, 4, calls)
+}
+
+func TestIdempotency_ConcurrentDuplicateGetsConflict(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := newMemKeys()
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	calls := 0
+
+	r := gin.New()
+	r.Use(fakeAuth, Idempotency(repo, time.Hour))
+	r.POST("/cashouts", func(c *gin.Context) {
+		calls++
+		close(started)
+		<-finish // La primera petición sigue trabajando mientras llega el duplicado
+		c.JSON(http.StatusCreated, gin.H{"id": "retiro-1"})
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- post(r, "/cashouts", "retry-5") }()
+	<-started
+
+	duplicate := post(r, "/cashouts", "retry-5")
+	assert.Equal(t, http.StatusConflict, duplicate.Code)
+	assert.Contains(t, duplicate.Body.String(), ErrCodeIdempotencyInProgress)
+
+	close(finish)
+	first := <-done
+	assert.Equal(t, http.StatusCreated, first.Code)
+
+	// Ya terminada, el reintento recibe la respuesta original
+	retry := post(r, "/cashouts", "retry-5")
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, 1, calls)
+}
+
+func TestIdempotency_SamePayloadInAnyOrderReplays(t *testing.T) {
+	repo := newMemKeys()
+	calls := 0
+	r := newIdempotentRouter(repo, http.StatusCreated, &calls)
+
+	postBody(r, "/transactions", "retry-3", `{"amount":"150.00","merchant_id":1,"reference":"CFE-1"}`)
+	// Mismo contenido con otro orden y otros espacios
+	second := postBody(r, "/transactions", "retry-3", `{ "reference": "CFE-1", "merchant_id": 1, "amount": "150.00" }`)
+
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.Equal(t, 1, calls)
+}
+
+func TestIdempotency_DifferentPayloadIsRejected(t *testing.T) {
+	repo := newMemKeys()
+	calls := 0
+	r := newIdempotentRouter(repo, http.StatusCreated, &calls)
+
+	postBody(r, "/transactions", "retry-4", `{"amount":"150.00","merchant_id":1,"reference":"CFE-1"}`)
+
+	for _, retry := range []struct{ path, body string }{
+		{"/transactions", `{"amount":"1500.00","merchant_id":1,"reference":"CFE-1"}`}, // Otro monto
+		{"/transactions", `{"amount":"150.00","merchant_id":2,"reference":"CFE-1"}`},  // Otro merchant
+	} {
+		w := postBody(r, retry.path, "retry-4", retry.body)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code, retry.body)
+		var res map[string]string
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, ErrCodeIdempotencyMismatch, res["code"])
+	}
+	assert.Equal(t, 1, calls)
+}
+
+func postAs(r *gin.Engine, clientID, path, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, nil)
+	req.Header.Set("X-Client", clientID)
+	req.Header.Set(IdempotencyHeader, key)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotency_KeysAreScopedByClientAndOperation(t *testing.T) {
+	repo := newMemKeys()
+	calls := 0
+	r := newIdempotentRouter(repo, http.StatusCreated, &calls)
+
+	first := postAs(r, "1", "/transactions", "mismo-uuid")
+	// Otro cliente con el mismo UUID no recibe la transacción del primero
+	other := postAs(r, "2", "/transactions", "mismo-uuid")
+	// El mismo cliente con la misma llave en otra operación es otra operación
+	deposit := postAs(r, "1", "/deposits", "mismo-uuid")
+
+	assert.Equal(t, 3, calls)
+	assert.NotEqual(t, first.Body.String(), other.Body.String())
+	assert.Contains(t, deposit.Body.String(), "/deposits")
+	assert.Len(t, repo.keys, 3)
+}
+
+func TestIdempotency_ExpiredKeyCanBeReused(t *testing.T) {
+	repo := newMemKeys(domain.IdempotencyKey{
+		IdempotencyScope: domain.IdempotencyScope{ClientID: 1, Operation: "POST /cashouts", Key: "vieja"},
+		Status:           domain.IdempotencyCompleted,
+		ResponseJSON:     `{"id":"retiro-viejo"}`,
+		StatusCode:       http.StatusCreated,
+		ExpiresAt:        time.Now().Add(-time.Minute),
+	})
+	calls := 0
+	r := newIdempotentRouter(repo, http.StatusCreated, &calls)
+
+	w := post(r, "/cashouts", "vieja")
+
+	assert.Equal(t, 1, calls)
+	assert.NotContains(t, w.Body.String(), "retiro-viejo")
+	saved := repo.keys[domain.IdempotencyScope{ClientID: 1, Operation: "POST /cashouts", Key: "vieja"}]
+	assert.True(t, saved.ExpiresAt.After(time.Now().Add(59*time.Minute)))
+}
+
+func TestIdempotency_ReplaysBusinessFailureWithHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := newMemKeys()
+	calls := 0
+
+	r := gin.New()
+	r.Use(fakeAuth, Idempotency(repo, time.Hour))
+	r.POST("/cashouts", func(c *gin.Context) {
+		calls++
+		c.Header("Location", "/api/v1/cashouts/123")
+		c.JSON(http.StatusUnprocessableEntity, ErrorResponse{Code: domain.ErrPaymentRejected.Code, Message: "referencia vencida"})
+	})
+
+	first := post(r, "/cashouts", "retry-6")
+	retry := post(r, "/cashouts", "retry-6")
+
+	assert.Equal(t, 1, calls)
+	assert.Empty(t, first.Header().Get(ReplayedHeader))
+	assert.Equal(t, http.StatusUnprocessableEntity, retry.Code)
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, "/api/v1/cashouts/123", retry.Header().Get("Location"))
+	assert.Equal(t, first.Header().Get("Content-Type"), retry.Header().Get("Content-Type"))
+	assert.Equal(t, "true", retry.Header().Get(ReplayedHeader))
+}
+
+func TestIdempotency_StateDependentFailureIsNotReplayed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := newMemKeys()
+	calls := 0
+
+	r := gin.New()
+	r.Use(fakeAuth, Idempotency(repo, time.Hour))
+	r.POST("/cashouts", func(c *gin.Context) {
+		calls++
+		if calls == 1 {
+			c.JSON(http.StatusUnprocessableEntity, ErrorResponse{Code: domain.ErrInsufficientFunds.Code, Message: "fondos insuficientes"})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"status": "COMPLETED"})
+	})
+
+	first := post(r, "/cashouts", "retry-7")
+	// El cliente recargó saldo: el reintento con la misma llave ya sale bien
+	retry := post(r, "/cashouts", "retry-7")
+
+	assert.Equal(t, http.StatusUnprocessableEntity, first.Code)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotency_KeyIsReleasedWhenResponseCannotBeSaved(t *testing.T) {
+	repo := newMemKeys()
+	repo.saveErr = errors.New("conexión perdida")
+	calls := 0
+	r := newIdempotentRouter(repo, http.StatusCreated, &calls)
+
+	post(r, "/transactions", "retry-8")
+
+	// Sin respuesta guardada la llave no se queda en proceso para siempre
+	assert.Empty(t, repo.keys)
+	assert.Equal(t, http.StatusCreated, post(r, "/transactions", "retry-8").Code)
+}
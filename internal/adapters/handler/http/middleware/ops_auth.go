@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OpsAuth protege las rutas de operaciones con su propia llave en X-OPS-KEY: la API key de un
+// cliente no sirve para ver el estado de todos los billers. Sin llave configurada las rutas quedan cerradas.
+func OpsAuth(key string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		given := c.GetHeader("X-OPS-KEY")
+		if given == "" {
+			AbortWithError(c, http.StatusUnauthorized, ErrorResponse{Code: ErrCodeAPIKeyRequired, Message: "Se requiere la llave de operaciones"})
+			return
+		}
+		if key == "" || subtle.ConstantTimeCompare([]byte(given), []byte(key)) != 1 {
+			AbortWithError(c, http.StatusForbidden, ErrorResponse{Code: ErrCodeInvalidAPIKey, Message: "Llave de operaciones inválida"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader viaja en la petición (si el cliente ya trae uno) y siempre en la respuesta
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDKey es donde queda el ID en el contexto de gin
+const RequestIDKey = "request_id"
+
+// maxRequestIDLength evita que un cliente meta un header enorme en nuestros logs
+const maxRequestIDLength = 100
+
+// RequestID le da a cada petición un ID para cruzar la respuesta del cliente con nuestros logs.
+// Va antes que todos los demás middlewares para que sus errores también lo lleven.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if id == "" || len(id) > maxRequestIDLength {
+			id = uuid.NewString()
+		}
+		c.Set(RequestIDKey, id)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
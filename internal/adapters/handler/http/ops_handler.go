@@ -0,0 +1,25 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/scorazag/gopayhub/internal/core/ports"
+)
+
+// OpsHandler expone el estado de las integraciones para operaciones; sus rutas piden la llave de
+// operaciones y no la de un cliente (ver middleware.OpsAuth)
+type OpsHandler struct {
+	circuits ports.MerchantCircuits
+}
+
+func NewOpsHandler(circuits ports.MerchantCircuits) *OpsHandler {
+	return &OpsHandler{circuits: circuits}
+}
+
+// ListMerchantCircuits atiende GET /ops/merchant-circuits
+func (h *OpsHandler) ListMerchantCircuits(c *gin.Context) {
+	// Mismo formato que los listados, en una sola página
+	c.JSON(http.StatusOK, domain.Page[domain.CircuitState]{Items: h.circuits.Circuits()})
+}
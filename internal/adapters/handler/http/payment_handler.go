@@ -2,16 +2,22 @@ package http
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/scorazag/gopayhub/internal/core/domain"
 	"github.com/scorazag/gopayhub/internal/core/ports"
 )
 
 // Definimos el struct para leer el JSON que viene de afuera
+// Con bill_inquiry_id (de GET /merchants/:id/bills) el monto y la referencia son opcionales: se toman del recibo
 type PaymentRequest struct {
-	Amount     float64 `json:"amount" binding:"required,gt=0"`
-	MerchantID uint    `json:"merchant_id" binding:"required"`
-	Reference  string  `json:"reference" binding:"required"`
+	Amount        domain.Money `json:"amount" binding:"required_without=BillInquiryID"`
+	Currency      string       `json:"currency"` // ISO 4217; vacío = MXN
+	MerchantID    uint         `json:"merchant_id" binding:"required"`
+	Reference     string       `json:"reference" binding:"required_without=BillInquiryID"`
+	BillInquiryID *uuid.UUID   `json:"bill_inquiry_id"`
 }
 
 type PaymentHandler struct {
@@ -26,13 +32,12 @@ func (h *PaymentHandler) ProcessTransaction(c *gin.Context) {
 	var req PaymentRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos: " + err.Error()})
+		respondInvalidRequest(c, "Datos inválidos: "+err.Error())
 		return
 	}
 
-	clientID, exists := c.Get("client_id")
-	if !exists {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al identificar al cliente"})
+	clientID, ok := authenticatedClientID(c)
+	if !ok {
 		return
 	}
 
@@ -40,18 +45,41 @@ func (h *PaymentHandler) ProcessTransaction(c *gin.Context) {
 	// Es estándar usar "X-Idempotency-Key" o "Idempotency-Key"
 	idemKey := c.GetHeader("X-Idempotency-Key")
 
-	// 4. Llamar al servicio
-	tx, err := h.service.ProcessPayment(
+	var billInquiryID uuid.UUID
+	if req.BillInquiryID != nil {
+		billInquiryID = *req.BillInquiryID
+	}
+
+	// 4. Llamar al servicio. Con "Prefer: respond-async" (RFC 7240) no esperamos al biller:
+	// contestamos 202 con la transacción en PENDING y se termina en segundo plano
+	process := h.service.ProcessPayment
+	async := strings.Contains(strings.ToLower(c.GetHeader("Prefer")), "respond-async")
+	if async {
+		process = h.service.ProcessPaymentAsync
+	}
+	tx, err := process(
 		req.Amount,
+		req.Currency,
 		req.MerchantID,
-		clientID.(uint),
+		clientID,
 		req.Reference,
+		billInquiryID,
 		idemKey,
 	)
 
 	if err != nil {
 		// Si el error es de negocio, devolvemos 422
-		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		respondError(c, err)
+		return
+	}
+
+	// Sigue PENDING si se pidió asíncrono o si el biller no contestó a tiempo: se termina en segundo plano
+	if tx.Status == "PENDING" {
+		if async {
+			c.Header("Preference-Applied", "respond-async")
+		}
+		c.Header("Location", "/api/v1/transactions/"+tx.ID.String())
+		c.JSON(http.StatusAccepted, tx)
 		return
 	}
 
@@ -0,0 +1,85 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/scorazag/gopayhub/internal/core/ports"
+)
+
+// QueryHandler expone la consulta de operaciones por ID y por llave de idempotencia.
+// Siempre filtra por el cliente autenticado: lo de otro cliente es 404.
+type QueryHandler struct {
+	service  ports.QueryService
+	location *time.Location // Zona horaria del negocio para los filtros por día
+}
+
+func NewQueryHandler(service ports.QueryService, location *time.Location) *QueryHandler {
+	return &QueryHandler{service: service, location: location}
+}
+
+func (h *QueryHandler) GetTransaction(c *gin.Context) {
+	id, clientID, ok := bindQueryID(c)
+	if !ok {
+		return
+	}
+	res, err := h.service.GetTransaction(id, clientID)
+	respondQuery(c, res, err)
+}
+
+func (h *QueryHandler) GetDeposit(c *gin.Context) {
+	id, clientID, ok := bindQueryID(c)
+	if !ok {
+		return
+	}
+	res, err := h.service.GetDeposit(id, clientID)
+	respondQuery(c, res, err)
+}
+
+func (h *QueryHandler) GetCashOut(c *gin.Context) {
+	id, clientID, ok := bindQueryID(c)
+	if !ok {
+		return
+	}
+	res, err := h.service.GetCashOut(id, clientID)
+	respondQuery(c, res, err)
+}
+
+func (h *QueryHandler) GetTransactionByIdempotencyKey(c *gin.Context) {
+	res, err := h.service.GetTransactionByIdempotencyKey(c.Param("key"), c.GetUint("client_id"))
+	respondQuery(c, res, err)
+}
+
+func (h *QueryHandler) GetDepositByIdempotencyKey(c *gin.Context) {
+	res, err := h.service.GetDepositByIdempotencyKey(c.Param("key"), c.GetUint("client_id"))
+	respondQuery(c, res, err)
+}
+
+func (h *QueryHandler) GetCashOutByIdempotencyKey(c *gin.Context) {
+	res, err := h.service.GetCashOutByIdempotencyKey(c.Param("key"), c.GetUint("client_id"))
+	respondQuery(c, res, err)
+}
+
+// bindQueryID lee el ID de la ruta y el cliente autenticado; si algo falla ya respondió
+func bindQueryID(c *gin.Context) (uuid.UUID, uint, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondInvalidRequest(c, "ID inválido")
+		return uuid.Nil, 0, false
+	}
+	clientID, ok := authenticatedClientID(c)
+	if !ok {
+		return uuid.Nil, 0, false
+	}
+	return id, clientID, true
+}
+
+func respondQuery(c *gin.Context, res any, err error) {
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,62 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/scorazag/gopayhub/internal/core/ports"
+)
+
+// RefundRequest: si no se manda amount se devuelve el total pendiente
+type RefundRequest struct {
+	Amount domain.Money `json:"amount" binding:"gte=0"`
+	Reason string       `json:"reason"`
+}
+
+type RefundHandler struct {
+	service ports.RefundService
+}
+
+func NewRefundHandler(service ports.RefundService) *RefundHandler {
+	return &RefundHandler{service: service}
+}
+
+func (h *RefundHandler) ProcessRefund(c *gin.Context) {
+	transactionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondInvalidRequest(c, "ID de transacción inválido")
+		return
+	}
+
+	// El body es opcional: sin body es una devolución total
+	var req RefundRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondInvalidRequest(c, "Datos inválidos: "+err.Error())
+		return
+	}
+
+	clientID, ok := authenticatedClientID(c)
+	if !ok {
+		return
+	}
+
+	idemKey := c.GetHeader("X-Idempotency-Key")
+
+	refund, err := h.service.RefundPayment(transactionID, req.Amount, clientID, req.Reason, idemKey)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// El biller no contestó: el monto queda apartado y se le vuelve a preguntar en segundo plano
+	if refund.Status == "PENDING" {
+		c.Header("Location", "/api/v1/transactions/"+refund.TransactionID.String())
+		c.JSON(http.StatusAccepted, refund)
+		return
+	}
+	c.JSON(http.StatusCreated, refund)
+}
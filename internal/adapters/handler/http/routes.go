@@ -0,0 +1,85 @@
+package http
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/scorazag/gopayhub/internal/adapters/handler/http/middleware"
+)
+
+// openAPISpec es el contrato de la API que ven los integradores. routes_test.go revisa que
+// describa exactamente las rutas de RegisterRoutes: una ruta nueva sin documentar rompe la prueba.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// Handlers agrupa los handlers que atienden las rutas de la API
+type Handlers struct {
+	Payment       *PaymentHandler
+	Deposit       *DepositHandler
+	CashOut       *CashOutHandler
+	Refund        *RefundHandler
+	Void          *VoidHandler
+	Authorization *AuthorizationHandler
+	Query         *QueryHandler
+	Account       *AccountHandler
+	Bill          *BillHandler
+	Ops           *OpsHandler
+}
+
+// RegisterRoutes registra todas las rutas de /api/v1. Health y el documento OpenAPI son públicos;
+// las de /ops solo pasan con opsAuth, y protected (auth, idempotencia) se aplica a todas las demás, en ese orden.
+func RegisterRoutes(r *gin.Engine, h Handlers, opsAuth gin.HandlerFunc, protected ...gin.HandlerFunc) {
+	// Cada respuesta (y cada error) lleva X-Request-ID para cruzarla con los logs
+	r.Use(middleware.RequestID())
+	r.NoRoute(func(c *gin.Context) {
+		middleware.AbortWithError(c, http.StatusNotFound, middleware.ErrorResponse{Code: middleware.ErrCodeRouteNotFound, Message: "Ruta no encontrada"})
+	})
+
+	api := r.Group("/api/v1")
+	{
+		// El health check lo dejamos fuera del auth para que AWS/Docker puedan revisarlo
+		api.GET("/health", func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{"status": "OK"})
+		})
+		api.GET("/openapi.json", func(c *gin.Context) {
+			c.Data(http.StatusOK, "application/json; charset=utf-8", openAPISpec)
+		})
+
+		// Operaciones: con su propia llave, no con la de un cliente.
+		// El grupo se crea antes de api.Use para que no le toque el middleware de los clientes.
+		ops := api.Group("/ops", opsAuth)
+		// Estado del circuit breaker de cada biller
+		ops.GET("/merchant-circuits", h.Ops.ListMerchantCircuits)
+
+		// Aplicamos el middleware a partir de aquí
+		api.Use(protected...)
+
+		api.POST("/transactions", h.Payment.ProcessTransaction)
+		api.POST("/deposits", h.Deposit.ProcessDeposit)
+		api.POST("/cashouts", h.CashOut.ProcessCashOut)
+		api.POST("/transactions/:id/refunds", h.Refund.ProcessRefund)
+		api.POST("/deposits/:id/void", h.Void.VoidDeposit)
+		api.POST("/cashouts/:id/void", h.Void.VoidCashOut)
+		api.POST("/authorizations", h.Authorization.Authorize)
+		api.POST("/transactions/:id/capture", h.Authorization.Capture)
+		api.POST("/transactions/:id/release", h.Authorization.Release)
+
+		// Consultas: solo regresan operaciones del cliente autenticado
+		api.GET("/transactions", h.Query.ListTransactions)
+		api.GET("/deposits", h.Query.ListDeposits)
+		api.GET("/cashouts", h.Query.ListCashOuts)
+		api.GET("/transactions/:id", h.Query.GetTransaction)
+		api.GET("/deposits/:id", h.Query.GetDeposit)
+		api.GET("/cashouts/:id", h.Query.GetCashOut)
+		api.GET("/transactions/by-key/:key", h.Query.GetTransactionByIdempotencyKey)
+		api.GET("/deposits/by-key/:key", h.Query.GetDepositByIdempotencyKey)
+		api.GET("/cashouts/by-key/:key", h.Query.GetCashOutByIdempotencyKey)
+		api.GET("/balance", h.Account.GetBalance)
+		api.GET("/statement", h.Account.GetStatement)
+
+		// Consulta del recibo antes de cobrar
+		api.GET("/merchants/:id/bills", h.Bill.InquireBill)
+	}
+}
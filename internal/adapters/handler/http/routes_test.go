@@ -0,0 +1,247 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/scorazag/gopayhub/internal/adapters/handler/http/middleware"
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openAPIDoc es lo que nos importa del documento para compararlo con el código
+type openAPIDoc struct {
+	OpenAPI    string                                `json:"openapi"`
+	Servers    []struct{ URL string }                `json:"servers"`
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+func loadSpec(t *testing.T) openAPIDoc {
+	var doc openAPIDoc
+	require.NoError(t, json.Unmarshal(openAPISpec, &doc))
+	return doc
+}
+
+func newTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	// Los handlers no se ejecutan: solo nos interesa qué rutas quedan registradas
+	RegisterRoutes(r, Handlers{}, middleware.OpsAuth(""))
+	return r
+}
+
+var pathParam = regexp.MustCompile(`\{(\w+)\}`)
+
+func TestOpenAPI_MatchesRoutes(t *testing.T) {
+	doc := loadSpec(t)
+	require.Len(t, doc.Servers, 1)
+	base := doc.Servers[0].URL
+
+	var documented []string
+	for path, ops := range doc.Paths {
+		for method := range ops {
+			documented = append(documented, strings.ToUpper(method)+" "+base+pathParam.ReplaceAllString(path, ":$1"))
+		}
+	}
+	var registered []string
+	for _, route := range newTestRouter().Routes() {
+		registered = append(registered, route.Method+" "+route.Path)
+	}
+
+	// Si falla, agregue la ruta nueva a openapi.json (o quite la que ya no existe)
+	assert.ElementsMatch(t, registered, documented)
+}
+
+// requestSchemas son los structs que reciben los handlers, por el nombre de su esquema en openapi.json
+var requestSchemas = map[string]any{
+	"PaymentRequest":       PaymentRequest{},
+	"AuthorizationRequest": AuthorizationRequest{},
+	"DepositRequest":       DepositRequest{},
+	"CashOutRequest":       CashOutRequest{},
+	"RefundRequest":        RefundRequest{},
+	"VoidRequest":          VoidRequest{},
+}
+
+func TestOpenAPI_RequestSchemasMatchStructs(t *testing.T) {
+	doc := loadSpec(t)
+	for name, req := range requestSchemas {
+		var fields []string
+		typ := reflect.TypeOf(req)
+		for i := 0; i < typ.NumField(); i++ {
+			fields = append(fields, strings.Split(typ.Field(i).Tag.Get("json"), ",")[0])
+		}
+		var documented []string
+		for prop := range doc.Components.Schemas[name].Properties {
+			documented = append(documented, prop)
+		}
+		assert.ElementsMatch(t, fields, documented, name)
+	}
+}
+
+// responseSchemas son los structs que responden los handlers, por el nombre de su esquema en openapi.json
+var responseSchemas = map[string]any{
+	"Error":            middleware.ErrorResponse{},
+	"Fee":              domain.Fee{},
+	"Transaction":      domain.Transaction{},
+	"Refund":           domain.Refund{},
+	"Deposit":          domain.Deposit{},
+	"CashOut":          domain.CashOut{},
+	"TransactionPage":  domain.Page[domain.Transaction]{},
+	"DepositPage":      domain.Page[domain.Deposit]{},
+	"CashOutPage":      domain.Page[domain.CashOut]{},
+	"Balance":          domain.Balance{},
+	"StatementLine":    domain.StatementLine{},
+	"Statement":        domain.Statement{},
+	"Bill":             domain.Bill{},
+	"CircuitState":     domain.CircuitState{},
+	"CircuitStatePage": domain.Page[domain.CircuitState]{},
+}
+
+// jsonFields son las llaves que encoding/json pone al serializar typ: los structs embebidos sin tag
+// aportan sus campos y los que tienen json:"-" no salen
+func jsonFields(typ reflect.Type) []string {
+	var fields []string
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		switch {
+		case name == "-" || !field.IsExported():
+		case field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct:
+			fields = append(fields, jsonFields(field.Type)...)
+		case name == "":
+			fields = append(fields, field.Name)
+		default:
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+// Lo que sale en las respuestas es lo documentado, ni más ni menos: un campo nuevo en el modelo
+// (como la relación con el cliente y su API key) no se filtra sin que nadie lo note
+func TestOpenAPI_ResponseSchemasMatchStructs(t *testing.T) {
+	doc := loadSpec(t)
+	for name, res := range responseSchemas {
+		var documented []string
+		for prop := range doc.Components.Schemas[name].Properties {
+			documented = append(documented, prop)
+		}
+		assert.ElementsMatch(t, jsonFields(reflect.TypeOf(res)), documented, name)
+	}
+}
+
+// Todo esquema con propiedades tiene que estar en requestSchemas o en responseSchemas
+func TestOpenAPI_SchemasAreChecked(t *testing.T) {
+	for name, schema := range loadSpec(t).Components.Schemas {
+		if len(schema.Properties) == 0 {
+			continue
+		}
+		_, isRequest := requestSchemas[name]
+		_, isResponse := responseSchemas[name]
+		assert.True(t, isRequest || isResponse, name)
+	}
+}
+
+// Todo body documentado tiene que estar en requestSchemas, si no su esquema se puede desviar sin que nadie lo note.
+// Captura y liberación no llevan body.
+func TestOpenAPI_RequestBodiesAreChecked(t *testing.T) {
+	doc := loadSpec(t)
+	for path, ops := range doc.Paths {
+		for method, raw := range ops {
+			var op struct {
+				RequestBody *struct {
+					Content map[string]struct {
+						Schema struct {
+							Ref string `json:"$ref"`
+						} `json:"schema"`
+					} `json:"content"`
+				} `json:"requestBody"`
+			}
+			require.NoError(t, json.Unmarshal(raw, &op))
+			if op.RequestBody == nil {
+				continue
+			}
+			for _, content := range op.RequestBody.Content {
+				name := strings.TrimPrefix(content.Schema.Ref, "#/components/schemas/")
+				assert.Contains(t, requestSchemas, name, "%s %s", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
+
+func TestOpenAPI_RefsResolve(t *testing.T) {
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(openAPISpec, &doc))
+
+	var walk func(v any)
+	walk = func(v any) {
+		switch node := v.(type) {
+		case map[string]any:
+			if ref, ok := node["$ref"].(string); ok {
+				var target any = doc
+				for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+					parent, _ := target.(map[string]any)
+					target = parent[part]
+				}
+				assert.NotNil(t, target, ref)
+			}
+			for _, child := range node {
+				walk(child)
+			}
+		case []any:
+			for _, child := range node {
+				walk(child)
+			}
+		}
+	}
+	walk(doc)
+}
+
+func TestOpenAPI_IsServedWithoutAuth(t *testing.T) {
+	w := httptest.NewRecorder()
+	newTestRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "3.0.3", loadSpec(t).OpenAPI)
+	assert.JSONEq(t, string(openAPISpec), w.Body.String())
+}
+
+// fixedCircuits es un ports.MerchantCircuits que siempre regresa los mismos estados
+type fixedCircuits []domain.CircuitState
+
+func (f fixedCircuits) Available(merchant *domain.Merchant) error { return nil }
+func (f fixedCircuits) Circuits() []domain.CircuitState           { return f }
+
+func TestOpsRoutes_RequireTheOpsKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	clientAuth := func(c *gin.Context) { c.Set("client_id", uint(1)) }
+	RegisterRoutes(r, Handlers{Ops: NewOpsHandler(fixedCircuits{{MerchantID: 1, MerchantName: "CFE", State: domain.CircuitOpen}})}, middleware.OpsAuth("llave-ops"), clientAuth)
+
+	get := func(router *gin.Engine, header, value string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/ops/merchant-circuits", nil)
+		req.Header.Set(header, value)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// La API key de un cliente no abre las rutas de operaciones
+	assert.Equal(t, http.StatusUnauthorized, get(r, "X-API-KEY", "llave-cliente"))
+	assert.Equal(t, http.StatusForbidden, get(r, "X-OPS-KEY", "otra"))
+	assert.Equal(t, http.StatusOK, get(r, "X-OPS-KEY", "llave-ops"))
+	// Sin llave configurada quedan cerradas
+	assert.Equal(t, http.StatusForbidden, get(newTestRouter(), "X-OPS-KEY", "cualquiera"))
+}
@@ -0,0 +1,75 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/scorazag/gopayhub/internal/core/ports"
+)
+
+type VoidRequest struct {
+	VoidedBy string `json:"voided_by" binding:"required"` // Ej: el cajero o supervisor que anula
+	Reason   string `json:"reason" binding:"required"`
+}
+
+type VoidHandler struct {
+	service ports.VoidService
+}
+
+func NewVoidHandler(service ports.VoidService) *VoidHandler {
+	return &VoidHandler{service: service}
+}
+
+func (h *VoidHandler) VoidDeposit(c *gin.Context) {
+	id, req, clientID, ok := h.bind(c)
+	if !ok {
+		return
+	}
+
+	res, err := h.service.VoidDeposit(id, clientID, req.VoidedBy, req.Reason)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *VoidHandler) VoidCashOut(c *gin.Context) {
+	id, req, clientID, ok := h.bind(c)
+	if !ok {
+		return
+	}
+
+	res, err := h.service.VoidCashOut(id, clientID, req.VoidedBy, req.Reason)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// bind lee el ID de la ruta, el body y el cliente autenticado; si algo falla ya respondió
+func (h *VoidHandler) bind(c *gin.Context) (uuid.UUID, VoidRequest, uint, bool) {
+	var req VoidRequest
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondInvalidRequest(c, "ID inválido")
+		return uuid.Nil, req, 0, false
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondInvalidRequest(c, "Datos inválidos: "+err.Error())
+		return uuid.Nil, req, 0, false
+	}
+
+	clientID, ok := authenticatedClientID(c)
+	if !ok {
+		return uuid.Nil, req, 0, false
+	}
+
+	return id, req, clientID, true
+}
@@ -0,0 +1,45 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/scorazag/gopayhub/internal/core/domain"
+)
+
+// WebhookNotifier implementa ports.ClientNotifier mandando la transacción (el mismo JSON que
+// GET /transactions/:id) en un POST al WebhookURL del cliente.
+type WebhookNotifier struct {
+	client *http.Client
+}
+
+func NewWebhookNotifier(timeout time.Duration) *WebhookNotifier {
+	return &WebhookNotifier{client: &http.Client{Timeout: timeout}}
+}
+
+func (n *WebhookNotifier) NotifyTransaction(client *domain.Client, tx *domain.Transaction) error {
+	payload, err := json.Marshal(tx)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, client.WebhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("WebhookURL inválido: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// El cliente puede recibir el mismo aviso más de una vez; con esto lo reconoce
+	req.Header.Set("X-Transaction-ID", tx.ID.String())
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("el webhook contestó HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
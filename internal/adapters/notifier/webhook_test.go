@@ -0,0 +1,35 @@
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookNotifier_NotifyTransaction(t *testing.T) {
+	tx := &domain.Transaction{ID: uuid.New(), Amount: domain.MustParseMoney("150"), Currency: "MXN", Status: "COMPLETED"}
+	var got domain.Transaction
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, tx.ID.String(), r.Header.Get("X-Transaction-ID"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	n := NewWebhookNotifier(time.Second)
+
+	assert.NoError(t, n.NotifyTransaction(&domain.Client{WebhookURL: srv.URL}, tx))
+	assert.Equal(t, tx.ID, got.ID)
+	assert.Equal(t, "COMPLETED", got.Status)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	assert.Error(t, n.NotifyTransaction(&domain.Client{WebhookURL: failing.URL}, tx))
+}
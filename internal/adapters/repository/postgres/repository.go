@@ -1,8 +1,15 @@
 package postgres
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
 	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/scorazag/gopayhub/internal/core/ports"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // PaymentRepository implementa la interfaz de puertos
@@ -14,28 +21,230 @@ func NewPaymentRepository(db *gorm.DB) *PaymentRepository {
 	return &PaymentRepository{db: db}
 }
 
+// notFound traduce el "record not found" de GORM al domain.ErrNotFound que entienden los servicios;
+// cualquier otro error (BD caída, timeout) pasa igual para no confundirlo con un registro inexistente
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.ErrNotFound
+	}
+	return err
+}
+
 func (r *PaymentRepository) GetMerchantByID(id uint) (*domain.Merchant, error) {
 	var merchant domain.Merchant
-	err := r.db.First(&merchant, id).Error
-	return &merchant, err
+	if err := r.db.First(&merchant, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &merchant, nil
 }
 
 func (r *PaymentRepository) CreateTransaction(tx *domain.Transaction) error {
 	return r.db.Create(tx).Error
 }
 
+func (r *PaymentRepository) GetTransactionForUpdate(id uuid.UUID) (*domain.Transaction, error) {
+	var tx domain.Transaction
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Fees").First(&tx, "id = ?", id).Error
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &tx, nil
+}
+
+func (r *PaymentRepository) SaveTransaction(tx *domain.Transaction) error {
+	return r.db.Omit(clause.Associations).Save(tx).Error
+}
+
+func (r *PaymentRepository) CreateRefund(refund *domain.Refund) error {
+	return r.db.Create(refund).Error
+}
+
+func (r *PaymentRepository) SaveRefund(refund *domain.Refund) error {
+	return r.db.Save(refund).Error
+}
+
+func (r *PaymentRepository) GetRefund(id uuid.UUID) (*domain.Refund, error) {
+	var refund domain.Refund
+	if err := r.db.First(&refund, "id = ?", id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &refund, nil
+}
+
 func (r *PaymentRepository) CreateDeposit(tx *domain.Deposit) error {
 	return r.db.Create(tx).Error
 }
 
-func (r *PaymentRepository) GetIdempotencyKey(key string) (*domain.IdempotencyKey, error) {
+func (r *PaymentRepository) GetDepositForUpdate(id uuid.UUID) (*domain.Deposit, error) {
+	var deposit domain.Deposit
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Fees").First(&deposit, "id = ?", id).Error
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &deposit, nil
+}
+
+func (r *PaymentRepository) SaveDeposit(deposit *domain.Deposit) error {
+	return r.db.Omit(clause.Associations).Save(deposit).Error
+}
+
+func (r *PaymentRepository) GetCashOutForUpdate(id uuid.UUID) (*domain.CashOut, error) {
+	var cashout domain.CashOut
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Fees").First(&cashout, "id = ?", id).Error
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &cashout, nil
+}
+
+func (r *PaymentRepository) SaveCashOut(cashout *domain.CashOut) error {
+	return r.db.Omit(clause.Associations).Save(cashout).Error
+}
+
+// findOwned busca un registro del cliente con sus comisiones; si no existe o es de otro cliente regresa domain.ErrNotFound
+func (r *PaymentRepository) findOwned(dest any, clientID uint, query string, arg any) error {
+	err := r.db.Preload("Fees").Where("client_id = ?", clientID).Where(query, arg).
+		Order("created_at DESC").First(dest).Error
+	return notFound(err)
+}
+
+func (r *PaymentRepository) GetTransaction(id uuid.UUID, clientID uint) (*domain.Transaction, error) {
+	var tx domain.Transaction
+	if err := r.findOwned(&tx, clientID, "id = ?", id); err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+func (r *PaymentRepository) GetDeposit(id uuid.UUID, clientID uint) (*domain.Deposit, error) {
+	var deposit domain.Deposit
+	if err := r.findOwned(&deposit, clientID, "id = ?", id); err != nil {
+		return nil, err
+	}
+	return &deposit, nil
+}
+
+func (r *PaymentRepository) GetCashOut(id uuid.UUID, clientID uint) (*domain.CashOut, error) {
+	var cashout domain.CashOut
+	if err := r.findOwned(&cashout, clientID, "id = ?", id); err != nil {
+		return nil, err
+	}
+	return &cashout, nil
+}
+
+func (r *PaymentRepository) GetTransactionByIdempotencyKey(key string, clientID uint) (*domain.Transaction, error) {
+	var tx domain.Transaction
+	if err := r.findOwned(&tx, clientID, "idempotency_key = ?", key); err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+func (r *PaymentRepository) GetDepositByIdempotencyKey(key string, clientID uint) (*domain.Deposit, error) {
+	var deposit domain.Deposit
+	if err := r.findOwned(&deposit, clientID, "idempotency_key = ?", key); err != nil {
+		return nil, err
+	}
+	return &deposit, nil
+}
+
+func (r *PaymentRepository) GetCashOutByIdempotencyKey(key string, clientID uint) (*domain.CashOut, error) {
+	var cashout domain.CashOut
+	if err := r.findOwned(&cashout, clientID, "idempotency_key = ?", key); err != nil {
+		return nil, err
+	}
+	return &cashout, nil
+}
+
+// listQuery aplica los filtros de un listado con el orden estable (created_at, id) de más reciente a más antiguo.
+// El cursor usa comparación de filas para que la página siguiente empiece justo después del último registro.
+func (r *PaymentRepository) listQuery(f domain.OperationFilter) *gorm.DB {
+	q := r.db.Preload("Fees").Where("client_id = ?", f.ClientID)
+	if f.From != nil {
+		q = q.Where("created_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		q = q.Where("created_at < ?", *f.To)
+	}
+	if f.Status != "" {
+		q = q.Where("status = ?", f.Status)
+	}
+	if f.MerchantID != nil {
+		q = q.Where("merchant_id = ?", *f.MerchantID)
+	}
+	if f.StoreName != "" {
+		q = q.Where("store_name = ?", f.StoreName)
+	}
+	if f.Reference != "" {
+		q = q.Where("reference = ?", f.Reference)
+	}
+	if f.MinAmount != nil {
+		q = q.Where("amount >= ?", *f.MinAmount)
+	}
+	if f.MaxAmount != nil {
+		q = q.Where("amount <= ?", *f.MaxAmount)
+	}
+	if f.After != nil {
+		q = q.Where("(created_at, id) < (?, ?)", f.After.CreatedAt, f.After.ID)
+	}
+	return q.Order("created_at DESC, id DESC").Limit(f.Limit)
+}
+
+func (r *PaymentRepository) ListTransactions(f domain.OperationFilter) ([]domain.Transaction, error) {
+	var txs []domain.Transaction
+	err := r.listQuery(f).Find(&txs).Error
+	return txs, err
+}
+
+func (r *PaymentRepository) ListDeposits(f domain.OperationFilter) ([]domain.Deposit, error) {
+	var deposits []domain.Deposit
+	err := r.listQuery(f).Find(&deposits).Error
+	return deposits, err
+}
+
+func (r *PaymentRepository) ListCashOuts(f domain.OperationFilter) ([]domain.CashOut, error) {
+	var cashouts []domain.CashOut
+	err := r.listQuery(f).Find(&cashouts).Error
+	return cashouts, err
+}
+
+func (r *PaymentRepository) GetIdempotencyKey(scope domain.IdempotencyScope) (*domain.IdempotencyKey, error) {
 	var idempotencyKey domain.IdempotencyKey
-	err := r.db.Where("key = ?", key).First(&idempotencyKey).Error
+	err := r.db.Where("client_id = ? AND operation = ? AND key = ?", scope.ClientID, scope.Operation, scope.Key).
+		First(&idempotencyKey).Error
 	return &idempotencyKey, err
 }
 
+// ClaimIdempotencyKey se apoya en la llave primaria: de dos INSERT simultáneos solo uno afecta una fila.
+// Una llave vencida que el job todavía no borra se reemplaza.
+func (r *PaymentRepository) ClaimIdempotencyKey(key *domain.IdempotencyKey) (bool, error) {
+	var claimed bool
+	err := r.Atomic(func(repo ports.PaymentRepository) error {
+		db := repo.(*PaymentRepository).db
+		err := db.Where("client_id = ? AND operation = ? AND key = ? AND expires_at <= NOW()", key.ClientID, key.Operation, key.Key).
+			Delete(&domain.IdempotencyKey{}).Error
+		if err != nil {
+			return err
+		}
+		res := db.Clauses(clause.OnConflict{DoNothing: true}).Create(key)
+		claimed = res.RowsAffected == 1
+		return res.Error
+	})
+	return claimed, err
+}
+
 func (r *PaymentRepository) SaveIdempotencyKey(key *domain.IdempotencyKey) error {
-	return r.db.Create(key).Error
+	return r.db.Save(key).Error
+}
+
+func (r *PaymentRepository) DeleteIdempotencyKey(scope domain.IdempotencyScope) error {
+	return r.db.Where("client_id = ? AND operation = ? AND key = ? AND status = ?", scope.ClientID, scope.Operation, scope.Key, domain.IdempotencyInProgress).
+		Delete(&domain.IdempotencyKey{}).Error
+}
+
+func (r *PaymentRepository) PurgeExpiredIdempotencyKeys(now time.Time) (int64, error) {
+	res := r.db.Where("expires_at <= ?", now).Delete(&domain.IdempotencyKey{})
+	return res.RowsAffected, res.Error
 }
 
 func (r *PaymentRepository) GetClientByApiKey(apiKey string) (*domain.Client, error) {
@@ -48,24 +257,316 @@ func (r *PaymentRepository) GetClientByApiKey(apiKey string) (*domain.Client, er
 	return &client, nil
 }
 
-func (r *PaymentRepository) GetClientBalance(clientID uint) (float64, error) {
-	var totalDeposits float64
-	var totalPayments float64
-	var totalCashOuts float64
+func (r *PaymentRepository) GetClientByID(id uint) (*domain.Client, error) {
+	var client domain.Client
+	if err := r.db.First(&client, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &client, nil
+}
 
-	// Sumar Depósitos
-	r.db.Model(&domain.Deposit{}).Where("client_id = ? AND status = ?", clientID, "COMPLETED").Select("COALESCE(sum(amount), 0)").Scan(&totalDeposits)
+func (r *PaymentRepository) GetTaxRate(region string) (*domain.TaxRate, error) {
+	var rate domain.TaxRate
+	if err := r.db.Where("region = ?", region).First(&rate).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &rate, nil
+}
 
-	// Sumar Pagos (Transactions)
-	r.db.Model(&domain.Transaction{}).Where("client_id = ? AND status = ?", clientID, "COMPLETED").Select("COALESCE(sum(amount), 0)").Scan(&totalPayments)
+// SeedTaxRates da de alta las tasas de IVA vigentes si todavía no existen; después se editan en la tabla
+func (r *PaymentRepository) SeedTaxRates() error {
+	defaults := []domain.TaxRate{
+		{Region: domain.RegionGeneral, Name: "IVA", RateBps: 1600},
+		{Region: domain.RegionFronteriza, Name: "IVA", RateBps: 800},
+	}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&defaults).Error
+}
 
-	// Sumar Retiros (CashOuts)
-	r.db.Model(&domain.CashOut{}).Where("client_id = ? AND status = ?", clientID, "COMPLETED").Select("COALESCE(sum(amount), 0)").Scan(&totalCashOuts)
+// GetClientBalance lee el saldo de la cuenta del cliente en el libro mayor (abonos - cargos)
+func (r *PaymentRepository) GetClientBalance(clientID uint, currency string) (domain.Money, error) {
+	var balance domain.Money
+	err := r.db.Model(&domain.Posting{}).
+		Where("account_code = ? AND currency = ?", domain.ClientAccountCode(clientID), currency).
+		Select("COALESCE(SUM(CASE WHEN direction = ? THEN amount ELSE -amount END), 0)", domain.Credit).
+		Scan(&balance).Error
+	return balance, err
+}
 
-	// Saldo final
-	return totalDeposits - totalPayments - totalCashOuts, nil
+// GetClientBalanceBefore es el saldo del cliente con las pólizas anteriores a la posición at (sin incluirla)
+func (r *PaymentRepository) GetClientBalanceBefore(clientID uint, currency string, at domain.PageCursor) (domain.Money, error) {
+	var balance domain.Money
+	err := r.db.Table("postings AS p").
+		Joins("JOIN journal_entries AS je ON je.id = p.journal_entry_id").
+		Where("p.account_code = ? AND p.currency = ?", domain.ClientAccountCode(clientID), currency).
+		Where("(je.created_at, je.id) < (?, ?)", at.CreatedAt, at.ID).
+		Select("COALESCE(SUM(CASE WHEN p.direction = ? THEN p.amount ELSE -p.amount END), 0)", domain.Credit).
+		Scan(&balance).Error
+	return balance, err
+}
+
+// ListStatementLines agrupa los asientos de la cuenta del cliente por póliza, del más antiguo al más reciente
+func (r *PaymentRepository) ListStatementLines(q domain.StatementQuery) ([]domain.StatementLine, error) {
+	query := r.db.Table("postings AS p").
+		Joins("JOIN journal_entries AS je ON je.id = p.journal_entry_id").
+		Where("p.account_code = ? AND p.currency = ?", domain.ClientAccountCode(q.ClientID), q.Currency)
+	if q.From != nil {
+		query = query.Where("je.created_at >= ?", *q.From)
+	}
+	if q.To != nil {
+		query = query.Where("je.created_at < ?", *q.To)
+	}
+	if q.After != nil {
+		query = query.Where("(je.created_at, je.id) > (?, ?)", q.After.CreatedAt, q.After.ID)
+	}
+	var lines []domain.StatementLine
+	err := query.
+		Select("je.id AS entry_id, je.operation_type, je.operation_id, je.description, je.created_at, "+
+			"SUM(CASE WHEN p.direction = ? THEN p.amount ELSE -p.amount END) AS amount", domain.Credit).
+		Group("je.id").
+		Order("je.created_at, je.id").
+		Limit(q.Limit).
+		Scan(&lines).Error
+	return lines, err
+}
+
+func (r *PaymentRepository) GetClientHolds(clientID uint, currency string) (domain.Money, error) {
+	var holds domain.Money
+	err := r.db.Model(&domain.Transaction{}).
+		Where("client_id = ? AND currency = ? AND status = ? AND expires_at > NOW()", clientID, currency, "AUTHORIZED").
+		Select("COALESCE(SUM(amount + fee_amount), 0)").
+		Scan(&holds).Error
+	return holds, err
+}
+
+func (r *PaymentRepository) ReleaseExpiredHolds(now time.Time) (int64, error) {
+	res := r.db.Model(&domain.Transaction{}).
+		Where("status = ? AND expires_at <= ?", "AUTHORIZED", now).
+		Update("status", "RELEASED")
+	return res.RowsAffected, res.Error
 }
 
 func (r *PaymentRepository) CreateCashOut(cashout *domain.CashOut) error {
 	return r.db.Create(cashout).Error
 }
+
+// PostJournalEntry valida y guarda una póliza; las cuentas se crean la primera vez que se usan
+func (r *PaymentRepository) PostJournalEntry(entry *domain.JournalEntry) error {
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+	return r.Atomic(func(repo ports.PaymentRepository) error {
+		db := repo.(*PaymentRepository).db
+		for _, p := range entry.Postings {
+			account := domain.NewLedgerAccount(p.AccountCode, p.Currency)
+			err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&account).Error
+			if err != nil {
+				return err
+			}
+		}
+		return db.Create(entry).Error
+	})
+}
+
+// LockClientAccount toma un SELECT ... FOR UPDATE sobre la cuenta del cliente.
+// Dos retiros concurrentes del mismo cliente se forman aquí y leen el saldo uno después del otro.
+func (r *PaymentRepository) LockClientAccount(clientID uint, currency string) error {
+	account := domain.NewLedgerAccount(domain.ClientAccountCode(clientID), currency)
+	if err := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&account).Error; err != nil {
+		return err
+	}
+	return r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("code = ? AND currency = ?", account.Code, account.Currency).
+		First(&domain.LedgerAccount{}).Error
+}
+
+func (r *PaymentRepository) LockLimit(id uint) error {
+	return r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&domain.Limit{}, id).Error
+}
+
+func (r *PaymentRepository) FindFeeSchedules(operationType string, currency string, clientID uint, merchantID uint, serviceType string) ([]domain.FeeSchedule, error) {
+	var schedules []domain.FeeSchedule
+	err := r.db.Preload("Tiers").
+		Where("operation_type = ? AND currency = ? AND is_active = ?", operationType, currency, true).
+		Where("client_id IS NULL OR client_id = ?", clientID).
+		Where("merchant_id IS NULL OR merchant_id = ?", merchantID).
+		Where("service_type = '' OR service_type IS NULL OR service_type = ?", serviceType).
+		Find(&schedules).Error
+	return schedules, err
+}
+
+func (r *PaymentRepository) FindLimits(op domain.LimitedOperation) ([]domain.Limit, error) {
+	var limits []domain.Limit
+	err := r.db.
+		Where("operation_type = ? AND currency = ? AND is_active = ?", op.OperationType, op.Currency, true).
+		Where("client_id IS NULL OR client_id = ?", op.ClientID).
+		Where("merchant_id IS NULL OR merchant_id = ?", op.MerchantID).
+		Where("store_name = '' OR store_name IS NULL OR store_name = ?", op.StoreName).
+		Order("id").
+		Find(&limits).Error
+	return limits, err
+}
+
+// SumOperations suma los montos del periodo; las retenciones vigentes cuentan como pagos
+func (r *PaymentRepository) SumOperations(q domain.LimitUsageQuery) (domain.Money, error) {
+	var model interface{}
+	switch q.OperationType {
+	case domain.OperationPayment:
+		model = &domain.Transaction{}
+	case domain.OperationDeposit:
+		model = &domain.Deposit{}
+	case domain.OperationCashOut:
+		model = &domain.CashOut{}
+	default:
+		return 0, fmt.Errorf("tipo de operación sin acumulado: %q", q.OperationType)
+	}
+
+	db := r.db.Model(model).
+		Where("currency = ? AND created_at >= ?", q.Currency, q.Since).
+		Where("status NOT IN ?", []string{"FAILED", "VOIDED", "RELEASED"})
+	if q.ClientID != nil {
+		db = db.Where("client_id = ?", *q.ClientID)
+	}
+	if q.MerchantID != nil {
+		db = db.Where("merchant_id = ?", *q.MerchantID)
+	}
+	if q.StoreName != "" {
+		db = db.Where("store_name = ?", q.StoreName)
+	}
+
+	var total domain.Money
+	err := db.Select("COALESCE(SUM(amount), 0)").Scan(&total).Error
+	return total, err
+}
+
+// SeedLimits da de alta los límites por omisión si todavía no existen; después se editan en la tabla
+func (r *PaymentRepository) SeedLimits() error {
+	defaults := []domain.Limit{
+		// El tope de depósitos en efectivo que antes estaba fijo en el código
+		{Code: "CASH_DEPOSIT_MAX", OperationType: domain.OperationDeposit, Period: domain.LimitPerOperation, Currency: domain.BaseCurrency, MaxAmount: domain.MoneyFromUnits(10000)},
+	}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&defaults).Error
+}
+
+func (r *PaymentRepository) EnqueuePaymentJob(job *domain.PaymentJob) error {
+	return r.db.Create(job).Error
+}
+
+// ClaimPaymentJob usa FOR UPDATE SKIP LOCKED: varios workers (o varios procesos) nunca toman el mismo job.
+// Un job apartado por un worker que se cayó vuelve a estar disponible cuando vence LockedUntil.
+func (r *PaymentRepository) ClaimPaymentJob(now time.Time, lease time.Duration) (*domain.PaymentJob, error) {
+	var claimed *domain.PaymentJob
+	err := r.Atomic(func(repo ports.PaymentRepository) error {
+		db := repo.(*PaymentRepository).db
+		var job domain.PaymentJob
+		err := db.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("run_at <= ? AND (locked_until IS NULL OR locked_until <= ?)", now, now).
+			Order("run_at, id").
+			First(&job).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		lockedUntil := now.Add(lease)
+		job.LockedUntil = &lockedUntil
+		job.Attempts++
+		if err := db.Save(&job).Error; err != nil {
+			return err
+		}
+		claimed = &job
+		return nil
+	})
+	return claimed, err
+}
+
+func (r *PaymentRepository) SavePaymentJob(job *domain.PaymentJob) error {
+	return r.db.Save(job).Error
+}
+
+func (r *PaymentRepository) DeletePaymentJob(id uint) error {
+	return r.db.Delete(&domain.PaymentJob{}, id).Error
+}
+
+func (r *PaymentRepository) EnqueueRefundJob(job *domain.RefundJob) error {
+	return r.db.Create(job).Error
+}
+
+// ClaimRefundJob aparta el siguiente job de devolución igual que ClaimPaymentJob
+func (r *PaymentRepository) ClaimRefundJob(now time.Time, lease time.Duration) (*domain.RefundJob, error) {
+	var claimed *domain.RefundJob
+	err := r.Atomic(func(repo ports.PaymentRepository) error {
+		db := repo.(*PaymentRepository).db
+		var job domain.RefundJob
+		err := db.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("run_at <= ? AND (locked_until IS NULL OR locked_until <= ?)", now, now).
+			Order("run_at, id").
+			First(&job).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		lockedUntil := now.Add(lease)
+		job.LockedUntil = &lockedUntil
+		job.Attempts++
+		if err := db.Save(&job).Error; err != nil {
+			return err
+		}
+		claimed = &job
+		return nil
+	})
+	return claimed, err
+}
+
+func (r *PaymentRepository) SaveRefundJob(job *domain.RefundJob) error {
+	return r.db.Save(job).Error
+}
+
+func (r *PaymentRepository) DeleteRefundJob(id uint) error {
+	return r.db.Delete(&domain.RefundJob{}, id).Error
+}
+
+func (r *PaymentRepository) Atomic(fn func(repo ports.PaymentRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&PaymentRepository{db: tx})
+	})
+}
+
+// BackfillLedger genera pólizas para las operaciones que se guardaron antes de tener libro mayor.
+// Es idempotente: solo toca operaciones que todavía no tienen póliza.
+func (r *PaymentRepository) BackfillLedger() error {
+	missing := "status = ? AND id NOT IN (SELECT operation_id FROM journal_entries WHERE operation_type = ?)"
+
+	var deposits []domain.Deposit
+	if err := r.db.Where(missing, "COMPLETED", domain.OperationDeposit).Find(&deposits).Error; err != nil {
+		return err
+	}
+	for i := range deposits {
+		if err := r.PostJournalEntry(domain.NewDepositEntry(&deposits[i])); err != nil {
+			return err
+		}
+	}
+
+	var payments []domain.Transaction
+	if err := r.db.Where(missing, "COMPLETED", domain.OperationPayment).Find(&payments).Error; err != nil {
+		return err
+	}
+	for i := range payments {
+		if err := r.PostJournalEntry(domain.NewPaymentEntry(&payments[i])); err != nil {
+			return err
+		}
+	}
+
+	var cashouts []domain.CashOut
+	if err := r.db.Where(missing, "COMPLETED", domain.OperationCashOut).Find(&cashouts).Error; err != nil {
+		return err
+	}
+	for i := range cashouts {
+		if err := r.PostJournalEntry(domain.NewCashOutEntry(&cashouts[i])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Bill es el recibo pendiente que regresa el biller al consultar una referencia.
+// La cajera lo ve antes de cobrar; el pago lo puede ligar con InquiryID.
+type Bill struct {
+	InquiryID    uuid.UUID  `json:"inquiry_id"`
+	MerchantID   uint       `json:"merchant_id"`
+	ClientID     uint       `json:"-"` // Solo el cliente que consultó puede cobrar con la consulta
+	Reference    string     `json:"reference"`
+	CustomerName string     `json:"customer_name"`
+	AmountDue    Money      `json:"amount_due"`
+	Currency     string     `json:"currency"`
+	DueDate      *time.Time `json:"due_date,omitempty"` // Algunos billers no manejan fecha límite
+	ExpiresAt    time.Time  `json:"expires_at"`         // Hasta cuándo se puede pagar con esta consulta
+}
+
+// Expired dice si la consulta ya no sirve para pagar
+func (b *Bill) Expired(now time.Time) bool {
+	return !now.Before(b.ExpiresAt)
+}
@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+// Estados del circuit breaker de cada biller
+const (
+	CircuitClosed   = "CLOSED"    // Las llamadas pasan normalmente
+	CircuitOpen     = "OPEN"      // El biller falló seguido; se rechaza sin llamarlo hasta RetryAt
+	CircuitHalfOpen = "HALF_OPEN" // Pasó RetryAt: una llamada de prueba decide si se cierra o se vuelve a abrir
+)
+
+// CircuitState es la foto del circuit breaker de un biller que ve operaciones
+type CircuitState struct {
+	MerchantID          uint       `json:"merchant_id"`
+	MerchantName        string     `json:"merchant_name"`
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"` // Solo el tipo de falla (timeout, HTTP 502); nunca la URL del biller
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+	RetryAt             *time.Time `json:"retry_at,omitempty"` // Desde cuándo se deja pasar la llamada de prueba
+}
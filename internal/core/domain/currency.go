@@ -0,0 +1,102 @@
+package domain
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// BaseCurrency es la moneda en la que operan los clientes si no indican otra
+const BaseCurrency = "MXN"
+
+// FXAccountCode es la cuenta puente donde se cruzan las monedas en una conversión
+const FXAccountCode = "FX:POSITION"
+
+var (
+	ErrInvalidCurrency     = &Error{Code: "INVALID_CURRENCY", Message: "moneda inválida"}
+	ErrUnsupportedCurrency = &Error{Code: "UNSUPPORTED_CURRENCY", Message: "moneda no soportada"}
+)
+
+// isoCurrencies son los códigos ISO 4217 vigentes con sus decimales.
+// Money maneja centavos, así que solo operamos las monedas de 2 decimales.
+var isoCurrencies = map[string]int{
+	"AED": 2, "ARS": 2, "AUD": 2, "BHD": 3, "BOB": 2, "BRL": 2, "BZD": 2, "CAD": 2,
+	"CHF": 2, "CLP": 0, "CNY": 2, "COP": 2, "CRC": 2, "CZK": 2, "DKK": 2, "DOP": 2,
+	"EUR": 2, "GBP": 2, "GTQ": 2, "HKD": 2, "HNL": 2, "HUF": 2, "IDR": 2, "ILS": 2,
+	"INR": 2, "ISK": 0, "JOD": 3, "JPY": 0, "KRW": 0, "KWD": 3, "MXN": 2, "MYR": 2,
+	"NIO": 2, "NOK": 2, "NZD": 2, "OMR": 3, "PAB": 2, "PEN": 2, "PHP": 2, "PLN": 2,
+	"PYG": 0, "RON": 2, "SAR": 2, "SEK": 2, "SGD": 2, "THB": 2, "TND": 3, "TRY": 2,
+	"TWD": 2, "USD": 2, "UYU": 2, "VND": 0, "ZAR": 2,
+}
+
+// NormalizeCurrency valida el código ISO 4217 y lo regresa en mayúsculas; vacío significa MXN
+func NormalizeCurrency(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return BaseCurrency, nil
+	}
+	decimals, ok := isoCurrencies[code]
+	if !ok {
+		return "", fmt.Errorf("%w: %q no es un código ISO 4217", ErrInvalidCurrency, code)
+	}
+	if decimals != 2 {
+		return "", fmt.Errorf("%w: %s maneja %d decimales", ErrUnsupportedCurrency, code, decimals)
+	}
+	return code, nil
+}
+
+// FXQuote es un tipo de cambio: 1 From = Rate To. Ej: 1 USD = 17.05 MXN
+type FXQuote struct {
+	From   string
+	To     string
+	Rate   string // Decimal exacto, nunca float
+	Source string // Ej: "banxico-fix"
+	AsOf   time.Time
+}
+
+// Convert aplica el tipo de cambio a un monto en From, redondeando al centavo (mitad hacia arriba)
+func (q FXQuote) Convert(amount Money) (Money, error) {
+	rate, ok := new(big.Rat).SetString(q.Rate)
+	if !ok || rate.Sign() <= 0 {
+		return 0, fmt.Errorf("tipo de cambio inválido %q", q.Rate)
+	}
+	converted := new(big.Rat).Mul(new(big.Rat).SetInt64(int64(amount)), rate)
+
+	// Redondeo mitad hacia arriba: floor(x + 1/2) para positivos
+	half := big.NewRat(1, 2)
+	if converted.Sign() < 0 {
+		half.Neg(half)
+	}
+	converted.Add(converted, half)
+	cents := new(big.Int).Quo(converted.Num(), converted.Denom())
+	if !cents.IsInt64() {
+		return 0, fmt.Errorf("%w: fuera de rango", ErrInvalidMoney)
+	}
+	return Money(cents.Int64()), nil
+}
+
+// FXConversion registra con qué tipo de cambio se liquidó una operación en otra moneda
+type FXConversion struct {
+	SettlementAmount   Money      `gorm:"type:numeric(18,2);not null;default:0"` // Lo que recibe el merchant en su moneda
+	SettlementCurrency string     `gorm:"size:3"`
+	FXRate             string     `gorm:"size:30"` // 1 Currency = FXRate SettlementCurrency
+	FXSource           string     `gorm:"size:50"`
+	FXRateAt           *time.Time // Fecha del tipo de cambio usado
+}
+
+// NewFXConversion registra la conversión de amount con la cotización dada
+func NewFXConversion(amount Money, quote *FXQuote) (FXConversion, error) {
+	settlement, err := quote.Convert(amount)
+	if err != nil {
+		return FXConversion{}, err
+	}
+	asOf := quote.AsOf
+	return FXConversion{
+		SettlementAmount:   settlement,
+		SettlementCurrency: quote.To,
+		FXRate:             quote.Rate,
+		FXSource:           quote.Source,
+		FXRateAt:           &asOf,
+	}, nil
+}
@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeCurrency(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+		err  error
+	}{
+		{"", "MXN", nil},
+		{"usd", "USD", nil},
+		{" EUR ", "EUR", nil},
+		{"XYZ", "", ErrInvalidCurrency},
+		{"US", "", ErrInvalidCurrency},
+		{"JPY", "", ErrUnsupportedCurrency}, // Sin centavos
+		{"KWD", "", ErrUnsupportedCurrency}, // Tres decimales
+	}
+	for _, c := range cases {
+		got, err := NormalizeCurrency(c.in)
+		if c.err != nil {
+			assert.ErrorIs(t, err, c.err, c.in)
+			continue
+		}
+		assert.NoError(t, err, c.in)
+		assert.Equal(t, c.want, got)
+	}
+}
+
+func TestFXQuote_Convert(t *testing.T) {
+	quote := FXQuote{From: "USD", To: "MXN", Rate: "17.0512"}
+
+	got, err := quote.Convert(MustParseMoney("10.00"))
+	assert.NoError(t, err)
+	assert.Equal(t, "170.51", got.String())
+
+	// 0.03 * 17.0512 = 0.511536 -> 0.51
+	got, _ = quote.Convert(MustParseMoney("0.03"))
+	assert.Equal(t, "0.51", got.String())
+
+	// Mitad hacia arriba: 1.00 * 0.125 = 0.125 -> 0.13
+	half := FXQuote{Rate: "0.125"}
+	got, _ = half.Convert(MustParseMoney("1"))
+	assert.Equal(t, "0.13", got.String())
+
+	_, err = FXQuote{Rate: "abc"}.Convert(MustParseMoney("1"))
+	assert.Error(t, err)
+	_, err = FXQuote{Rate: "0"}.Convert(MustParseMoney("1"))
+	assert.Error(t, err)
+}
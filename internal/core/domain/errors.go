@@ -0,0 +1,64 @@
+package domain
+
+// Error es un error de negocio con un código estable. Los clientes deben decidir con Code
+// y no con el mensaje, que puede cambiar. Para dar detalle se envuelve: fmt.Errorf("%w: ...", ErrX).
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// ErrorCode regresa el código que ve el cliente
+func (e *Error) ErrorCode() string { return e.Code }
+
+// CodedError es cualquier error que trae su código (Error, LimitExceededError)
+type CodedError interface {
+	error
+	ErrorCode() string
+}
+
+// Validación de la petición
+var (
+	ErrInvalidAmount       = &Error{Code: "INVALID_AMOUNT", Message: "el monto debe ser mayor a cero"}
+	ErrInvalidFilter       = &Error{Code: "INVALID_FILTER", Message: "filtro inválido"}
+	ErrVoidDetailsRequired = &Error{Code: "VOID_DETAILS_REQUIRED", Message: "se requiere quién anula y el motivo"}
+	ErrReferenceRequired   = &Error{Code: "REFERENCE_REQUIRED", Message: "falta la referencia del recibo"}
+	ErrInvalidReference    = &Error{Code: "INVALID_REFERENCE", Message: "referencia inválida"}
+)
+
+// Registros que no existen. Lo de otro cliente tampoco existe: no revelamos qué IDs hay.
+var (
+	ErrNotFound            = &Error{Code: "NOT_FOUND", Message: "registro no encontrado"}
+	ErrMerchantNotFound    = &Error{Code: "MERCHANT_NOT_FOUND", Message: "proveedor de servicio no encontrado"}
+	ErrTransactionNotFound = &Error{Code: "TRANSACTION_NOT_FOUND", Message: "transacción no encontrada"}
+	ErrDepositNotFound     = &Error{Code: "DEPOSIT_NOT_FOUND", Message: "depósito no encontrado"}
+	ErrCashOutNotFound     = &Error{Code: "CASHOUT_NOT_FOUND", Message: "retiro no encontrado"}
+	ErrBillNotFound        = &Error{Code: "BILL_NOT_FOUND", Message: "el biller no tiene un recibo pendiente con esa referencia"}
+)
+
+// Reglas de negocio
+var (
+	ErrInsufficientFunds       = &Error{Code: "INSUFFICIENT_FUNDS", Message: "fondos insuficientes"}
+	ErrFeeExceedsAmount        = &Error{Code: "FEE_EXCEEDS_AMOUNT", Message: "la comisión excede el monto del depósito"}
+	ErrLimitExceeded           = &Error{Code: "LIMIT_EXCEEDED", Message: "se rebasó un límite de operación"}
+	ErrRefundExceedsBalance    = &Error{Code: "REFUND_EXCEEDS_REMAINING", Message: "el monto excede lo que resta por devolver de la transacción"}
+	ErrFXRateUnavailable       = &Error{Code: "FX_RATE_UNAVAILABLE", Message: "no hay tipo de cambio"}
+	ErrPaymentRejected         = &Error{Code: "PAYMENT_REJECTED", Message: "el biller rechazó el pago"}
+	ErrRefundRejected          = &Error{Code: "REFUND_REJECTED", Message: "el biller rechazó la devolución"}
+	ErrMerchantUnavailable     = &Error{Code: "MERCHANT_UNAVAILABLE", Message: "el biller no respondió"}
+	ErrBillInquiryExpired      = &Error{Code: "BILL_INQUIRY_EXPIRED", Message: "la consulta del recibo no existe o ya venció; hay que consultarlo otra vez"}
+	ErrBillInquiryMismatch     = &Error{Code: "BILL_INQUIRY_MISMATCH", Message: "el pago no corresponde a la consulta del recibo"}
+	ErrReferenceExpired        = &Error{Code: "REFERENCE_EXPIRED", Message: "el recibo ya venció"}
+	ErrReferenceAmountMismatch = &Error{Code: "REFERENCE_AMOUNT_MISMATCH", Message: "el monto no coincide con el del recibo"}
+)
+
+// La operación no está en un estado que lo permita
+var (
+	ErrNotRefundable    = &Error{Code: "TRANSACTION_NOT_REFUNDABLE", Message: "solo se pueden devolver transacciones completadas"}
+	ErrNoActiveHold     = &Error{Code: "NO_ACTIVE_HOLD", Message: "la transacción no tiene una retención activa"}
+	ErrHoldExpired      = &Error{Code: "HOLD_EXPIRED", Message: "la retención ya expiró"}
+	ErrAlreadyVoided    = &Error{Code: "ALREADY_VOIDED", Message: "la operación ya fue anulada"}
+	ErrNotVoidable      = &Error{Code: "OPERATION_NOT_VOIDABLE", Message: "solo se pueden anular operaciones completadas"}
+	ErrVoidWindowClosed = &Error{Code: "VOID_WINDOW_CLOSED", Message: "la ventana para anular la operación ya cerró"}
+)
@@ -0,0 +1,129 @@
+package domain
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Tipos de esquema de comisión
+const (
+	FeeFlat       = "FLAT"       // Monto fijo por operación
+	FeePercentage = "PERCENTAGE" // Porcentaje del monto
+	FeeTiered     = "TIERED"     // Fijo + porcentaje según el rango del monto
+)
+
+// FeeRevenueAccountCode es la cuenta de ingresos donde caen las comisiones cobradas
+const FeeRevenueAccountCode = "REVENUE:FEES"
+
+// FeeSchedule es un esquema de comisión configurable.
+// ClientID, MerchantID y ServiceType vacíos funcionan como comodín; gana el esquema más específico.
+type FeeSchedule struct {
+	ID            uint   `gorm:"primaryKey"`
+	OperationType string `gorm:"size:20;index;not null"` // PAYMENT, DEPOSIT, CASHOUT
+	ClientID      *uint  `gorm:"index"`
+	MerchantID    *uint  `gorm:"index"`
+	ServiceType   string `gorm:"size:50;index"`              // Ej: "ELECTRICITY"
+	Currency      string `gorm:"size:3;index;default:'MXN'"` // Los montos fijos están en esta moneda
+	Kind          string `gorm:"size:20;not null"`
+	FlatAmount    Money  `gorm:"type:numeric(18,2);not null;default:0"`
+	RateBps       int64  `gorm:"not null;default:0"` // Puntos base: 150 = 1.50%
+	MinFee        Money  `gorm:"type:numeric(18,2);not null;default:0"`
+	MaxFee        Money  `gorm:"type:numeric(18,2);not null;default:0"` // 0 = sin tope
+	Tiers         []FeeTier
+	IsActive      bool `gorm:"default:true"`
+	CreatedAt     time.Time
+}
+
+// FeeTier es un rango de un esquema TIERED: aplica a montos hasta UpTo (0 = sin límite)
+type FeeTier struct {
+	ID            uint  `gorm:"primaryKey"`
+	FeeScheduleID uint  `gorm:"index;not null"`
+	UpTo          Money `gorm:"type:numeric(18,2);not null;default:0"`
+	FlatAmount    Money `gorm:"type:numeric(18,2);not null;default:0"`
+	RateBps       int64 `gorm:"not null;default:0"`
+}
+
+// Fee es una línea de comisión cobrada en una operación (Transaction, Deposit o CashOut)
+type Fee struct {
+	ID            uint      `gorm:"primaryKey"`
+	OperationID   uuid.UUID `gorm:"type:uuid;index"`
+	OperationType string    `gorm:"size:20;index"` // PAYMENT, DEPOSIT, CASHOUT
+	FeeScheduleID uint
+	Description   string `gorm:"size:200"`
+	Amount        Money  `gorm:"type:numeric(18,2);not null"` // Comisión antes de impuestos
+	// Desglose de IVA para facturación
+	TaxName    string `gorm:"size:20"`
+	TaxBase    Money  `gorm:"type:numeric(18,2);not null;default:0"`
+	TaxRateBps int64  `gorm:"not null;default:0"`
+	TaxAmount  Money  `gorm:"type:numeric(18,2);not null;default:0"`
+	CreatedAt  time.Time
+}
+
+// Specificity ordena esquemas: cliente pesa más que merchant, y merchant más que tipo de servicio
+func (s FeeSchedule) Specificity() int {
+	score := 0
+	if s.ClientID != nil {
+		score += 4
+	}
+	if s.MerchantID != nil {
+		score += 2
+	}
+	if s.ServiceType != "" {
+		score++
+	}
+	return score
+}
+
+// Calculate regresa la comisión para un monto, ya con mínimo y máximo aplicados
+func (s FeeSchedule) Calculate(amount Money) Money {
+	var fee Money
+	switch s.Kind {
+	case FeeFlat:
+		fee = s.FlatAmount
+	case FeePercentage:
+		fee = s.FlatAmount + ApplyRate(amount, s.RateBps)
+	case FeeTiered:
+		tiers := append([]FeeTier(nil), s.Tiers...)
+		sort.Slice(tiers, func(i, j int) bool {
+			// Los rangos sin límite (UpTo = 0) van al final
+			if tiers[i].UpTo == 0 || tiers[j].UpTo == 0 {
+				return tiers[j].UpTo == 0 && tiers[i].UpTo != 0
+			}
+			return tiers[i].UpTo < tiers[j].UpTo
+		})
+		for _, tier := range tiers {
+			if tier.UpTo == 0 || amount <= tier.UpTo {
+				fee = tier.FlatAmount + ApplyRate(amount, tier.RateBps)
+				break
+			}
+		}
+	}
+
+	if fee < s.MinFee {
+		fee = s.MinFee
+	}
+	if s.MaxFee > 0 && fee > s.MaxFee {
+		fee = s.MaxFee
+	}
+	return fee
+}
+
+// ApplyRate calcula amount * bps / 10,000 redondeando al centavo más cercano (mitad hacia arriba)
+func ApplyRate(amount Money, bps int64) Money {
+	product := int64(amount) * bps
+	if product >= 0 {
+		return Money((product + 5000) / 10000)
+	}
+	return Money((product - 5000) / 10000)
+}
+
+// TotalFees suma lo que se le cobra al cliente por comisiones, IVA incluido
+func TotalFees(fees []Fee) Money {
+	var total Money
+	for _, f := range fees {
+		total += f.Amount + f.TaxAmount
+	}
+	return total
+}
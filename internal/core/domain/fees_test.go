@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeeSchedule_Calculate(t *testing.T) {
+	flat := FeeSchedule{Kind: FeeFlat, FlatAmount: MustParseMoney("12.00")}
+	assert.Equal(t, MustParseMoney("12.00"), flat.Calculate(MustParseMoney("5000")))
+
+	// 1.5% con mínimo de $5 y tope de $50
+	pct := FeeSchedule{Kind: FeePercentage, RateBps: 150, MinFee: MustParseMoney("5"), MaxFee: MustParseMoney("50")}
+	assert.Equal(t, MustParseMoney("15.00"), pct.Calculate(MustParseMoney("1000")))
+	assert.Equal(t, MustParseMoney("5.00"), pct.Calculate(MustParseMoney("100")))
+	assert.Equal(t, MustParseMoney("50.00"), pct.Calculate(MustParseMoney("10000")))
+
+	// Hasta $500: $8 fijos; hasta $2,000: 1%; arriba: $10 + 0.5%
+	tiered := FeeSchedule{Kind: FeeTiered, Tiers: []FeeTier{
+		{UpTo: 0, FlatAmount: MustParseMoney("10"), RateBps: 50},
+		{UpTo: MustParseMoney("2000"), RateBps: 100},
+		{UpTo: MustParseMoney("500"), FlatAmount: MustParseMoney("8")},
+	}}
+	assert.Equal(t, MustParseMoney("8.00"), tiered.Calculate(MustParseMoney("500")))
+	assert.Equal(t, MustParseMoney("15.00"), tiered.Calculate(MustParseMoney("1500")))
+	assert.Equal(t, MustParseMoney("25.00"), tiered.Calculate(MustParseMoney("3000")))
+}
+
+func TestApplyRate_RoundsHalfUp(t *testing.T) {
+	// 1.5% de $0.33 = 0.495 centavos -> 0; de $0.34 = 0.51 -> 1 centavo
+	assert.Equal(t, Money(0), ApplyRate(MustParseMoney("0.33"), 150))
+	assert.Equal(t, Money(1), ApplyRate(MustParseMoney("0.34"), 150))
+	// 16% de $12.34 = 1.9744 -> 1.97
+	assert.Equal(t, MustParseMoney("1.97"), ApplyRate(MustParseMoney("12.34"), 1600))
+}
+
+func TestPaymentEntry_ChargesFees(t *testing.T) {
+	tx := &Transaction{Amount: MustParseMoney("100"), ClientID: 1, MerchantID: 2, Fees: []Fee{{Amount: MustParseMoney("7.50")}}}
+	entry := NewPaymentEntry(tx)
+
+	assert.NoError(t, entry.Validate())
+	assert.Len(t, entry.Postings, 4)
+	assert.Equal(t, FeeRevenueAccountCode, entry.Postings[3].AccountCode)
+	assert.Equal(t, MustParseMoney("7.50"), entry.Postings[3].Amount)
+}
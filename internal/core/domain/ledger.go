@@ -0,0 +1,258 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Tipos de cuenta contable
+const (
+	AccountTypeAsset     = "ASSET"     // Lo que tenemos (efectivo en tiendas)
+	AccountTypeLiability = "LIABILITY" // Lo que debemos (saldo de clientes, pagos a merchants)
+	AccountTypeRevenue   = "REVENUE"   // Lo que ganamos (comisiones)
+)
+
+// Lado de cada asiento
+const (
+	Debit  = "DEBIT"
+	Credit = "CREDIT"
+)
+
+// Tipos de operación que generan pólizas
+const (
+	OperationDeposit = "DEPOSIT"
+	OperationPayment = "PAYMENT"
+	OperationCashOut = "CASHOUT"
+	OperationRefund  = "REFUND"
+	OperationVoid    = "VOID"
+)
+
+// CashAccountCode es la cuenta de efectivo que reciben/entregan las tiendas
+const CashAccountCode = "CASH:STORES"
+
+var ErrUnbalancedEntry = errors.New("la póliza no está balanceada")
+
+// LedgerAccount es una cuenta del libro mayor. Ej: "CLIENT:1", "MERCHANT:3".
+// Cada código tiene un saldo independiente por moneda.
+type LedgerAccount struct {
+	ID        uint   `gorm:"primaryKey"`
+	Code      string `gorm:"size:100;uniqueIndex:idx_ledger_account_code_currency;not null"`
+	Type      string `gorm:"size:20;not null"` // ASSET, LIABILITY, REVENUE
+	ClientID  *uint  `gorm:"index"`
+	Currency  string `gorm:"size:3;uniqueIndex:idx_ledger_account_code_currency;default:'MXN'"`
+	CreatedAt time.Time
+}
+
+// JournalEntry (póliza) agrupa los asientos de una operación; siempre debe cuadrar
+type JournalEntry struct {
+	ID            uuid.UUID `gorm:"type:uuid;primaryKey"`
+	OperationType string    `gorm:"size:20;index"` // DEPOSIT, PAYMENT, CASHOUT, REFUND, VOID
+	OperationID   uuid.UUID `gorm:"type:uuid;index"`
+	Description   string    `gorm:"size:200"`
+	Postings      []Posting
+	CreatedAt     time.Time
+}
+
+// Posting es un asiento (cargo o abono) contra una cuenta
+type Posting struct {
+	ID             uint      `gorm:"primaryKey"`
+	JournalEntryID uuid.UUID `gorm:"type:uuid;index;not null"`
+	AccountCode    string    `gorm:"size:100;index;not null"`
+	Direction      string    `gorm:"size:6;not null"` // DEBIT, CREDIT
+	Amount         Money     `gorm:"type:numeric(18,2);not null"`
+	Currency       string    `gorm:"size:3;not null;default:'MXN'"`
+	CreatedAt      time.Time
+}
+
+func (e *JournalEntry) BeforeCreate(tx *gorm.DB) (err error) {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
+
+// ClientAccountCode regresa la cuenta donde vive el saldo del cliente
+func ClientAccountCode(clientID uint) string {
+	return fmt.Sprintf("CLIENT:%d", clientID)
+}
+
+// MerchantAccountCode regresa la cuenta por pagar al merchant
+func MerchantAccountCode(merchantID uint) string {
+	return fmt.Sprintf("MERCHANT:%d", merchantID)
+}
+
+// Validate revisa que la póliza tenga asientos positivos y que cargos = abonos en cada moneda
+func (e *JournalEntry) Validate() error {
+	if len(e.Postings) < 2 {
+		return fmt.Errorf("%w: se requieren al menos dos asientos", ErrUnbalancedEntry)
+	}
+	net := map[string]Money{} // cargos - abonos por moneda
+	for _, p := range e.Postings {
+		if p.Amount <= 0 {
+			return fmt.Errorf("%w: monto no positivo en %s", ErrUnbalancedEntry, p.AccountCode)
+		}
+		switch p.Direction {
+		case Debit:
+			net[p.Currency] += p.Amount
+		case Credit:
+			net[p.Currency] -= p.Amount
+		default:
+			return fmt.Errorf("%w: dirección desconocida %q", ErrUnbalancedEntry, p.Direction)
+		}
+	}
+	for currency, diff := range net {
+		if diff != 0 {
+			return fmt.Errorf("%w: en %s los cargos exceden a los abonos por %s", ErrUnbalancedEntry, currency, diff)
+		}
+	}
+	return nil
+}
+
+// currencyOrBase trata la moneda vacía como MXN (registros anteriores a multi-moneda)
+func currencyOrBase(currency string) string {
+	if currency == "" {
+		return BaseCurrency
+	}
+	return currency
+}
+
+// NewDepositEntry: entra efectivo a la tienda y crece el saldo del cliente
+func NewDepositEntry(d *Deposit) *JournalEntry {
+	cur := currencyOrBase(d.Currency)
+	entry := &JournalEntry{
+		OperationType: OperationDeposit,
+		OperationID:   d.ID,
+		Description:   "Depósito " + d.Reference,
+		Postings: []Posting{
+			{AccountCode: CashAccountCode, Direction: Debit, Amount: d.Amount, Currency: cur},
+			{AccountCode: ClientAccountCode(d.ClientID), Direction: Credit, Amount: d.Amount, Currency: cur},
+		},
+	}
+	entry.Postings = append(entry.Postings, feePostings(d.ClientID, cur, d.Fees)...)
+	return entry
+}
+
+// NewPaymentEntry: baja el saldo del cliente y se lo debemos al merchant.
+// Si el merchant cobra en otra moneda, el cruce pasa por la cuenta FX al tipo de cambio registrado.
+func NewPaymentEntry(t *Transaction) *JournalEntry {
+	cur := currencyOrBase(t.Currency)
+	entry := &JournalEntry{
+		OperationType: OperationPayment,
+		OperationID:   t.ID,
+		Description:   "Pago " + t.Reference,
+		Postings:      settlementPostings(t.ClientID, t.MerchantID, Debit, t.Amount, cur, t.FXConversion),
+	}
+	entry.Postings = append(entry.Postings, feePostings(t.ClientID, cur, t.Fees)...)
+	return entry
+}
+
+// NewCashOutEntry: baja el saldo del cliente y sale efectivo de la tienda
+func NewCashOutEntry(c *CashOut) *JournalEntry {
+	cur := currencyOrBase(c.Currency)
+	entry := &JournalEntry{
+		OperationType: OperationCashOut,
+		OperationID:   c.ID,
+		Description:   "Retiro " + c.Reference,
+		Postings: []Posting{
+			{AccountCode: ClientAccountCode(c.ClientID), Direction: Debit, Amount: c.Amount, Currency: cur},
+			{AccountCode: CashAccountCode, Direction: Credit, Amount: c.Amount, Currency: cur},
+		},
+	}
+	entry.Postings = append(entry.Postings, feePostings(c.ClientID, cur, c.Fees)...)
+	return entry
+}
+
+// settlementPostings mueve dinero entre el cliente y el merchant. clientSide es Debit para pagos
+// y Credit para devoluciones; el merchant siempre queda del lado contrario.
+// Los asientos siguen el flujo del dinero: del cliente al merchant en pagos y al revés en devoluciones.
+func settlementPostings(clientID, merchantID uint, clientSide string, amount Money, cur string, fx FXConversion) []Posting {
+	merchantSide := Credit
+	if clientSide == Credit {
+		merchantSide = Debit
+	}
+	postings := []Posting{{AccountCode: ClientAccountCode(clientID), Direction: clientSide, Amount: amount, Currency: cur}}
+
+	if fx.SettlementCurrency == "" || fx.SettlementCurrency == cur {
+		postings = append(postings,
+			Posting{AccountCode: MerchantAccountCode(merchantID), Direction: merchantSide, Amount: amount, Currency: cur},
+		)
+	} else {
+		postings = append(postings,
+			Posting{AccountCode: FXAccountCode, Direction: merchantSide, Amount: amount, Currency: cur},
+			Posting{AccountCode: FXAccountCode, Direction: clientSide, Amount: fx.SettlementAmount, Currency: fx.SettlementCurrency},
+			Posting{AccountCode: MerchantAccountCode(merchantID), Direction: merchantSide, Amount: fx.SettlementAmount, Currency: fx.SettlementCurrency},
+		)
+	}
+	if clientSide == Credit {
+		slices.Reverse(postings)
+	}
+	return postings
+}
+
+// feePostings cobra cada comisión del saldo del cliente hacia la cuenta de ingresos, y su IVA hacia la cuenta de impuestos
+func feePostings(clientID uint, cur string, fees []Fee) []Posting {
+	var postings []Posting
+	for _, f := range fees {
+		if f.Amount > 0 {
+			postings = append(postings,
+				Posting{AccountCode: ClientAccountCode(clientID), Direction: Debit, Amount: f.Amount, Currency: cur},
+				Posting{AccountCode: FeeRevenueAccountCode, Direction: Credit, Amount: f.Amount, Currency: cur},
+			)
+		}
+		if f.TaxAmount > 0 {
+			postings = append(postings,
+				Posting{AccountCode: ClientAccountCode(clientID), Direction: Debit, Amount: f.TaxAmount, Currency: cur},
+				Posting{AccountCode: TaxAccountCode, Direction: Credit, Amount: f.TaxAmount, Currency: cur},
+			)
+		}
+	}
+	return postings
+}
+
+// NewRefundEntry: el merchant nos regresa el dinero y se lo devolvemos al cliente (al tipo de cambio del pago original)
+func NewRefundEntry(r *Refund, t *Transaction) *JournalEntry {
+	return &JournalEntry{
+		OperationType: OperationRefund,
+		OperationID:   r.ID,
+		Description:   "Devolución " + t.Reference,
+		Postings:      settlementPostings(t.ClientID, t.MerchantID, Credit, r.Amount, currencyOrBase(r.Currency), r.FXConversion),
+	}
+}
+
+// NewVoidEntry invierte los asientos de la póliza original; la operación anulada deja de contar en el saldo
+func NewVoidEntry(original *JournalEntry) *JournalEntry {
+	entry := &JournalEntry{
+		OperationType: OperationVoid,
+		OperationID:   original.OperationID,
+		Description:   "Anulación: " + original.Description,
+	}
+	for _, p := range original.Postings {
+		reversed := Posting{AccountCode: p.AccountCode, Direction: Credit, Amount: p.Amount, Currency: p.Currency}
+		if p.Direction == Credit {
+			reversed.Direction = Debit
+		}
+		entry.Postings = append(entry.Postings, reversed)
+	}
+	return entry
+}
+
+// NewLedgerAccount deduce el tipo de cuenta a partir de su código
+func NewLedgerAccount(code string, currency string) LedgerAccount {
+	account := LedgerAccount{Code: code, Type: AccountTypeLiability, Currency: currencyOrBase(currency)}
+	switch code {
+	case CashAccountCode, FXAccountCode:
+		account.Type = AccountTypeAsset
+	case FeeRevenueAccountCode:
+		account.Type = AccountTypeRevenue
+	}
+	var clientID uint
+	if _, err := fmt.Sscanf(code, "CLIENT:%d", &clientID); err == nil {
+		account.ClientID = &clientID
+	}
+	return account
+}
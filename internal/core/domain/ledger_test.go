@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJournalEntry_Validate(t *testing.T) {
+	entry := &JournalEntry{Postings: []Posting{
+		{AccountCode: CashAccountCode, Direction: Debit, Amount: MustParseMoney("100")},
+		{AccountCode: ClientAccountCode(1), Direction: Credit, Amount: MustParseMoney("99.99")},
+	}}
+	assert.ErrorIs(t, entry.Validate(), ErrUnbalancedEntry)
+
+	entry.Postings[1].Amount = MustParseMoney("100")
+	assert.NoError(t, entry.Validate())
+
+	// Un solo asiento nunca cuadra
+	single := &JournalEntry{Postings: entry.Postings[:1]}
+	assert.ErrorIs(t, single.Validate(), ErrUnbalancedEntry)
+}
+
+func TestOperationEntries_AreBalanced(t *testing.T) {
+	amount := MustParseMoney("250.50")
+	entries := []*JournalEntry{
+		NewDepositEntry(&Deposit{Amount: amount, ClientID: 7}),
+		NewPaymentEntry(&Transaction{Amount: amount, ClientID: 7, MerchantID: 3}),
+		NewCashOutEntry(&CashOut{Amount: amount, ClientID: 7}),
+	}
+	for _, e := range entries {
+		assert.NoError(t, e.Validate(), e.OperationType)
+	}
+
+	// El depósito abona al cliente; pago y retiro le cargan
+	assert.Equal(t, Credit, entries[0].Postings[1].Direction)
+	assert.Equal(t, ClientAccountCode(7), entries[1].Postings[0].AccountCode)
+	assert.Equal(t, Debit, entries[2].Postings[0].Direction)
+}
+
+func TestNewLedgerAccount(t *testing.T) {
+	client := NewLedgerAccount(ClientAccountCode(42), "USD")
+	assert.Equal(t, AccountTypeLiability, client.Type)
+	assert.Equal(t, uint(42), *client.ClientID)
+	assert.Equal(t, "USD", client.Currency)
+
+	cash := NewLedgerAccount(CashAccountCode, "")
+	assert.Equal(t, AccountTypeAsset, cash.Type)
+	assert.Nil(t, cash.ClientID)
+	assert.Equal(t, BaseCurrency, cash.Currency)
+}
+
+func TestJournalEntry_ValidatePerCurrency(t *testing.T) {
+	// Cuadra en el total, pero no dentro de cada moneda
+	entry := &JournalEntry{Postings: []Posting{
+		{AccountCode: ClientAccountCode(1), Direction: Debit, Amount: MustParseMoney("10"), Currency: "USD"},
+		{AccountCode: MerchantAccountCode(1), Direction: Credit, Amount: MustParseMoney("10"), Currency: "MXN"},
+	}}
+	assert.ErrorIs(t, entry.Validate(), ErrUnbalancedEntry)
+}
+
+func TestNewPaymentEntry_CrossCurrency(t *testing.T) {
+	quote := &FXQuote{From: "USD", To: "MXN", Rate: "17.05", Source: "test"}
+	fx, err := NewFXConversion(MustParseMoney("10"), quote)
+	assert.NoError(t, err)
+
+	entry := NewPaymentEntry(&Transaction{Amount: MustParseMoney("10"), Currency: "USD", ClientID: 7, MerchantID: 3, FXConversion: fx})
+	assert.NoError(t, entry.Validate())
+	assert.Len(t, entry.Postings, 4)
+
+	// El cliente paga en dólares y al merchant se le deben pesos
+	assert.Equal(t, Posting{AccountCode: ClientAccountCode(7), Direction: Debit, Amount: MustParseMoney("10"), Currency: "USD"}, entry.Postings[0])
+	assert.Equal(t, Posting{AccountCode: MerchantAccountCode(3), Direction: Credit, Amount: MustParseMoney("170.50"), Currency: "MXN"}, entry.Postings[3])
+
+	// La devolución usa la misma conversión y regresa todo a como estaba
+	refund := NewRefundEntry(&Refund{Amount: MustParseMoney("10"), Currency: "USD", FXConversion: fx}, &Transaction{ClientID: 7, MerchantID: 3})
+	assert.NoError(t, refund.Validate())
+	assert.Equal(t, Posting{AccountCode: MerchantAccountCode(3), Direction: Debit, Amount: MustParseMoney("170.50"), Currency: "MXN"}, refund.Postings[0])
+	assert.Equal(t, Posting{AccountCode: ClientAccountCode(7), Direction: Credit, Amount: MustParseMoney("10"), Currency: "USD"}, refund.Postings[3])
+}
@@ -0,0 +1,106 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// Periodos de un límite
+const (
+	LimitPerOperation = "PER_OPERATION" // Tope por operación individual
+	LimitDaily        = "DAILY"         // Acumulado del día hábil
+	LimitMonthly      = "MONTHLY"       // Acumulado del mes calendario
+)
+
+// Limit es un tope configurable por tipo de operación.
+// ClientID, MerchantID y StoreName vacíos funcionan como comodín; a diferencia de las comisiones,
+// aplican todos los límites que coincidan, no solo el más específico.
+type Limit struct {
+	ID            uint   `gorm:"primaryKey"`
+	Code          string `gorm:"size:50;uniqueIndex;not null"` // Se regresa al cliente cuando se rebasa. Ej: "CASH_DEPOSIT_MAX"
+	OperationType string `gorm:"size:20;index;not null"`       // PAYMENT, DEPOSIT, CASHOUT
+	Period        string `gorm:"size:20;not null"`             // PER_OPERATION, DAILY, MONTHLY
+	ClientID      *uint  `gorm:"index"`
+	MerchantID    *uint  `gorm:"index"`
+	StoreName     string `gorm:"size:100;index"`
+	Currency      string `gorm:"size:3;index;default:'MXN'"`
+	MaxAmount     Money  `gorm:"type:numeric(18,2);not null"`
+	// Por omisión el acumulado es de cada cliente ("ningún cliente deposita más de 50,000 al día").
+	// Shared suma a todos los clientes del alcance. Ej: el efectivo que puede recibir una tienda al día.
+	Shared    bool
+	IsActive  bool `gorm:"default:true"`
+	CreatedAt time.Time
+}
+
+// LimitedOperation es la operación que se quiere hacer, tal como la ven los límites
+type LimitedOperation struct {
+	OperationType string
+	Currency      string
+	Amount        Money
+	ClientID      uint
+	MerchantID    uint   // 0 en depósitos y retiros
+	StoreName     string // Vacío en pagos
+}
+
+// LimitUsageQuery describe qué operaciones ya hechas cuentan para el acumulado de un límite
+type LimitUsageQuery struct {
+	OperationType string
+	Currency      string
+	ClientID      *uint
+	MerchantID    *uint
+	StoreName     string
+	Since         time.Time
+}
+
+// PeriodStart regresa desde cuándo se acumula el límite; now ya debe venir en la zona horaria del negocio
+func (l Limit) PeriodStart(now time.Time) time.Time {
+	y, m, d := now.Date()
+	switch l.Period {
+	case LimitDaily:
+		return time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	case LimitMonthly:
+		return time.Date(y, m, 1, 0, 0, 0, 0, now.Location())
+	}
+	return now
+}
+
+// UsageQuery arma la consulta del acumulado para la operación: mismo alcance que el límite
+func (l Limit) UsageQuery(op LimitedOperation, since time.Time) LimitUsageQuery {
+	q := LimitUsageQuery{OperationType: l.OperationType, Currency: op.Currency, Since: since}
+	if l.ClientID != nil || !l.Shared {
+		clientID := op.ClientID
+		q.ClientID = &clientID
+	}
+	if l.MerchantID != nil {
+		merchantID := op.MerchantID
+		q.MerchantID = &merchantID
+	}
+	if l.StoreName != "" {
+		q.StoreName = op.StoreName
+	}
+	return q
+}
+
+// LimitExceededError dice qué límite se rebasó y por cuánto
+type LimitExceededError struct {
+	Limit     Limit
+	Used      Money // Acumulado del periodo antes de esta operación
+	Attempted Money
+}
+
+func (e *LimitExceededError) Error() string {
+	if e.Limit.Period == LimitPerOperation {
+		return fmt.Sprintf("el monto %s excede el límite %s de %s por operación", e.Attempted, e.Limit.Code, e.Limit.MaxAmount)
+	}
+	return fmt.Sprintf("la operación excede el límite %s: %s de %s ya usados en el periodo", e.Limit.Code, e.Used, e.Limit.MaxAmount)
+}
+
+// Is hace que errors.Is(err, ErrLimitExceeded) reconozca cualquier límite rebasado
+func (e *LimitExceededError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// ErrorCode es el código que se regresa al cliente. Ej: DAILY_LIMIT_EXCEEDED
+func (e *LimitExceededError) ErrorCode() string {
+	return e.Limit.Period + "_LIMIT_EXCEEDED"
+}
@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Tamaño de página de los listados
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 200
+)
+
+var ErrInvalidCursor = &Error{Code: "INVALID_CURSOR", Message: "cursor inválido"}
+
+// PageCursor apunta a la última operación de la página anterior. Los listados van del más reciente
+// al más antiguo por CreatedAt y, si empatan, por ID; así una operación nueva no mueve las páginas siguientes.
+type PageCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// Encode regresa el cursor opaco que se le da al cliente
+func (c PageCursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodePageCursor lee un cursor generado por Encode
+func DecodePageCursor(s string) (*PageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	c := PageCursor{}
+	if c.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.ID, err = uuid.Parse(id); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// OperationFilter son los filtros de un listado. Los campos vacíos no filtran.
+// MerchantID solo aplica a transacciones y StoreName solo a depósitos y retiros.
+type OperationFilter struct {
+	ClientID   uint       // Siempre el cliente autenticado
+	From       *time.Time // Inclusivo
+	To         *time.Time // Exclusivo
+	Status     string
+	MerchantID *uint
+	StoreName  string
+	Reference  string
+	MinAmount  *Money // Inclusivo
+	MaxAmount  *Money // Inclusivo
+	After      *PageCursor
+	Limit      int
+}
+
+// Validate revisa que los rangos tengan sentido
+func (f OperationFilter) Validate() error {
+	if f.From != nil && f.To != nil && !f.From.Before(*f.To) {
+		return fmt.Errorf("%w: from debe ser anterior a to", ErrInvalidFilter)
+	}
+	if f.MinAmount != nil && f.MaxAmount != nil && *f.MinAmount > *f.MaxAmount {
+		return fmt.Errorf("%w: min_amount es mayor que max_amount", ErrInvalidFilter)
+	}
+	if f.Limit < 0 || f.Limit > MaxPageSize {
+		return fmt.Errorf("%w: el tamaño de página debe estar entre 1 y %d", ErrInvalidFilter, MaxPageSize)
+	}
+	return nil
+}
+
+// Page es una página de un listado; NextCursor viene vacío en la última
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
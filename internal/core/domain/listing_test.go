@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPageCursor_RoundTrip(t *testing.T) {
+	c := PageCursor{CreatedAt: time.Date(2024, 5, 1, 10, 30, 0, 123456000, time.UTC), ID: uuid.New()}
+
+	decoded, err := DecodePageCursor(c.Encode())
+
+	assert.NoError(t, err)
+	assert.True(t, c.CreatedAt.Equal(decoded.CreatedAt))
+	assert.Equal(t, c.ID, decoded.ID)
+}
+
+func TestDecodePageCursor_Invalid(t *testing.T) {
+	for _, s := range []string{"no-es-base64!", "c2luLXNlcGFyYWRvcg", PageCursor{}.Encode()[:10]} {
+		_, err := DecodePageCursor(s)
+		assert.ErrorIs(t, err, ErrInvalidCursor, s)
+	}
+}
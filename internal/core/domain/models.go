@@ -8,69 +8,167 @@ import (
 )
 
 type Client struct {
-	ID        uint   `gorm:"primaryKey"`
-	Name      string `gorm:"size:100;not null"`    // Ej: "Oxxo Sucursal Centro"
-	ApiKey    string `gorm:"uniqueIndex;not null"` // Ej: "sk_live_12345"
-	IsActive  bool   `gorm:"default:true"`
-	CreatedAt time.Time
+	ID         uint   `gorm:"primaryKey"`
+	Name       string `gorm:"size:100;not null"`             // Ej: "Oxxo Sucursal Centro"
+	ApiKey     string `gorm:"uniqueIndex;not null" json:"-"` // Ej: "sk_live_12345"; nunca sale en una respuesta
+	IsActive   bool   `gorm:"default:true"`
+	Region     string `gorm:"size:20;default:'GENERAL'"` // Región fiscal: GENERAL, FRONTERIZA
+	WebhookURL string `gorm:"size:300"`                  // Si lo tiene, aquí le avisamos cuando termina un pago asíncrono
+	CreatedAt  time.Time
 }
 
+// MaxMerchantTimeout es lo más que se espera al biller en una llamada, sin importar lo configurado.
+// Los jobs de pagos y devoluciones se apartan por un poco más que esto; con un timeout más largo el
+// apartado vencería a media llamada y otro worker le preguntaría lo mismo al biller al mismo tiempo.
+const MaxMerchantTimeout = 4 * time.Minute
+
 type Merchant struct {
 	ID             uint   `gorm:"primaryKey"`
 	Name           string `gorm:"size:100"` // Ej: "CFE", "Netflix"
 	ServiceType    string `gorm:"index"`    // Ej: "ELECTRICITY", "STREAMING"
 	IntegrationURL string
+	Currency       string `gorm:"size:3;default:'MXN'"` // Moneda en la que cobra el biller
+	TimeoutMS      int    // Cuánto esperamos a su IntegrationURL; 0 = MERCHANT_TIMEOUT. Topado a MaxMerchantTimeout
 	CreatedAt      time.Time
 }
 
+// PaymentConfirmation es lo que contesta el biller cuando le avisamos de un pago
+type PaymentConfirmation struct {
+	Approved            bool
+	AuthorizationNumber string // Folio del biller; solo si aprobó
+	Message             string // Motivo, si rechazó
+}
+
 type Transaction struct {
-	ID             uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4()"`
-	Amount         float64   `gorm:"not null"`
+	ID             uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4()"`
+	Amount         Money      `gorm:"type:numeric(18,2);not null"`
+	Currency       string     `gorm:"size:3;default:'MXN'"`
+	Status         string     `gorm:"size:20;index"`                         // PENDING, COMPLETED, FAILED, AUTHORIZED, RELEASED, PARTIALLY_REFUNDED, REFUNDED
+	Reference      string     `gorm:"not null;index"`                        // Referencia del recibo de luz
+	RefundedAmount Money      `gorm:"type:numeric(18,2);not null;default:0"` // Incluye las devoluciones PENDING mientras el biller contesta
+	ExpiresAt      *time.Time `gorm:"index"`                                 // Solo para AUTHORIZED: cuándo se libera la retención sola
+	FeeAmount      Money      `gorm:"type:numeric(18,2);not null;default:0"` // Suma de Fees, se cobra aparte del monto
+	Fees           []Fee      `gorm:"polymorphic:Operation;polymorphicValue:PAYMENT"`
+	FXConversion              // Solo si el merchant cobra en otra moneda
+	ClientID       uint       `gorm:"index:idx_transactions_client_created,priority:1"`
+	Client         Client     `json:"-"` // Solo para la relación en la BD; no se expone en las respuestas
+	MerchantID     uint       `gorm:"index"`
+	Merchant       Merchant   `json:"-"`
+	CreatedAt      time.Time  `gorm:"index:idx_transactions_client_created,priority:2"` // Orden de los listados
+	IdempotencyKey string     `gorm:"size:100;index"`                                   // Relación lógica
+
+	// Respuesta del biller al avisarle del pago
+	AuthorizationNumber string `gorm:"size:100"` // Folio del biller cuando aplica el pago
+	FailureReason       string `gorm:"size:200"` // Por qué no se aplicó (FAILED)
+
+	// Consulta del recibo con la que se cobró (GET /merchants/:id/bills), si la hubo
+	BillInquiryID *uuid.UUID `gorm:"type:uuid"`
+}
+
+// Refund es una devolución (total o parcial) ligada a la transacción original
+type Refund struct {
+	ID             uuid.UUID `gorm:"type:uuid;primaryKey"`
+	TransactionID  uuid.UUID `gorm:"type:uuid;index;not null"`
+	Amount         Money     `gorm:"type:numeric(18,2);not null"`
 	Currency       string    `gorm:"size:3;default:'MXN'"`
-	Status         string    `gorm:"size:20;index"` // PENDING, COMPLETED, FAILED
-	Reference      string    `gorm:"not null"`      // Referencia del recibo de luz
-	ClientID       uint
-	Client         Client
-	MerchantID     uint
-	Merchant       Merchant
+	Status         string    `gorm:"size:20;index"` // PENDING (esperando al biller, que puede no haber contestado), COMPLETED, FAILED
+	Reason         string    `gorm:"size:200"`
+	FailureReason  string    `gorm:"size:200"` // Por qué el biller rechazó la devolución (FAILED)
+	FXConversion             // Mismo tipo de cambio que el pago original
+	ClientID       uint      `gorm:"not null"`
 	CreatedAt      time.Time
-	IdempotencyKey string `gorm:"size:100;index"` // Relación lógica
+	IdempotencyKey string `gorm:"size:100;index"`
+}
+
+// Estados de una llave de idempotencia
+const (
+	IdempotencyInProgress = "IN_PROGRESS" // Apartada; la primera petición sigue trabajando
+	IdempotencyCompleted  = "COMPLETED"   // Ya tiene respuesta para repetir
+)
+
+// IdempotencyScope identifica una llave: la misma llave de dos clientes, o de dos operaciones, son llaves distintas
+type IdempotencyScope struct {
+	ClientID  uint   `gorm:"primaryKey;autoIncrement:false"`
+	Operation string `gorm:"primaryKey;size:150"` // Método y ruta. Ej: "POST /api/v1/transactions"
+	Key       string `gorm:"primaryKey;size:100"` // El UUID que manda Oxxo
+}
+
+// PaymentJob es un pago asíncrono que falta confirmar con el biller.
+// Vive en la BD para que un reinicio no pierda pagos ya cobrados al cliente.
+type PaymentJob struct {
+	ID            uint       `gorm:"primaryKey"`
+	TransactionID uuid.UUID  `gorm:"type:uuid;uniqueIndex;not null"`
+	ClientID      uint       `gorm:"not null"`
+	Attempts      int        `gorm:"not null;default:0"` // Cuántas veces se le ha preguntado al biller
+	RunAt         time.Time  `gorm:"index;not null"`     // No se toma antes de esta hora (reintentos)
+	LockedUntil   *time.Time // Un worker lo tiene hasta esta hora; si el proceso se cae, otro lo retoma
+	LastError     string     `gorm:"size:300"`
+	CreatedAt     time.Time
+}
+
+// RefundJob es una devolución que el biller no contestó; se le vuelve a preguntar como a los pagos
+type RefundJob struct {
+	ID          uint       `gorm:"primaryKey"`
+	RefundID    uuid.UUID  `gorm:"type:uuid;uniqueIndex;not null"`
+	ClientID    uint       `gorm:"not null"`
+	Attempts    int        `gorm:"not null;default:0"`
+	RunAt       time.Time  `gorm:"index;not null"`
+	LockedUntil *time.Time // La petición que creó la devolución lo tiene apartado mientras le pregunta al biller
+	LastError   string     `gorm:"size:300"`
+	CreatedAt   time.Time
 }
 
 // Tabla para evitar doble cobro
 type IdempotencyKey struct {
-	Key          string `gorm:"primaryKey"` // El UUID que manda Oxxo
-	ResponseJSON string // Guardamos qué le respondimos la primera vez
-	StatusCode   int    // 200, 400, etc.
-	CreatedAt    time.Time
+	IdempotencyScope
+	RequestHash     string `gorm:"size:64"` // SHA-256 de ruta + cuerpo canónico; un reintento debe mandar lo mismo
+	Status          string `gorm:"size:20"` // IN_PROGRESS, COMPLETED
+	ResponseJSON    string // Guardamos qué le respondimos la primera vez
+	ResponseHeaders string // Headers relevantes de la respuesta, en JSON. Ej: {"Location": "..."}
+	StatusCode      int    // 201, 422, etc.
+	CreatedAt       time.Time
+	ExpiresAt       time.Time `gorm:"index"` // Después de esta fecha la llave se puede volver a usar y el job la borra
+}
+
+// VoidInfo registra quién anuló una operación, cuándo y por qué
+type VoidInfo struct {
+	VoidedAt   *time.Time `json:"voided_at,omitempty"`
+	VoidedBy   string     `gorm:"size:100" json:"voided_by,omitempty"`
+	VoidReason string     `gorm:"size:200" json:"void_reason,omitempty"`
 }
 
 type Deposit struct {
 	ID             uuid.UUID `gorm:"type:uuid;primaryKey"`
-	Amount         float64   `gorm:"not null"`
+	Amount         Money     `gorm:"type:numeric(18,2);not null"`
 	Currency       string    `gorm:"size:3;default:'MXN'"`
-	Status         string    `gorm:"size:20;index"` // PENDING, COMPLETED, FAILED
-	Reference      string    `gorm:"not null"`
-	StoreName      string    `gorm:"size:100"`       // Ej: "OXXO Tacubaya"
+	Status         string    `gorm:"size:20;index"` // PENDING, COMPLETED, FAILED, VOIDED
+	Reference      string    `gorm:"not null;index"`
+	StoreName      string    `gorm:"size:100;index"` // Ej: "OXXO Tacubaya"
 	ExternalID     string    `gorm:"size:100;index"` // ID que te da el corresponsal
-	ClientID       uint      `gorm:"not null"`
-	Client         Client    `gorm:"foreignKey:ClientID"`
-	CreatedAt      time.Time
-	IdempotencyKey string `gorm:"size:100;index"`
+	FeeAmount      Money     `gorm:"type:numeric(18,2);not null;default:0"`
+	Fees           []Fee     `gorm:"polymorphic:Operation;polymorphicValue:DEPOSIT"`
+	ClientID       uint      `gorm:"not null;index:idx_deposits_client_created,priority:1"`
+	Client         Client    `gorm:"foreignKey:ClientID" json:"-"`
+	CreatedAt      time.Time `gorm:"index:idx_deposits_client_created,priority:2"`
+	IdempotencyKey string    `gorm:"size:100;index"`
+	VoidInfo
 }
 
 type CashOut struct {
 	ID             uuid.UUID `gorm:"type:uuid;primaryKey"`
-	Amount         float64   `gorm:"not null"`
+	Amount         Money     `gorm:"type:numeric(18,2);not null"`
 	Currency       string    `gorm:"size:3;default:'MXN'"`
-	Status         string    `gorm:"size:20;index"` // PENDING, COMPLETED, FAILED
-	Reference      string    `gorm:"not null"`
-	StoreName      string    `gorm:"size:100"`       // Ej: "OXXO Tacubaya"
+	Status         string    `gorm:"size:20;index"` // PENDING, COMPLETED, FAILED, VOIDED
+	Reference      string    `gorm:"not null;index"`
+	StoreName      string    `gorm:"size:100;index"` // Ej: "OXXO Tacubaya"
 	ExternalID     string    `gorm:"size:100;index"` // ID que te da el corresponsal
-	ClientID       uint      `gorm:"not null"`
-	Client         Client    `gorm:"foreignKey:ClientID"`
-	CreatedAt      time.Time
-	IdempotencyKey string `gorm:"size:100;index"`
+	FeeAmount      Money     `gorm:"type:numeric(18,2);not null;default:0"`
+	Fees           []Fee     `gorm:"polymorphic:Operation;polymorphicValue:CASHOUT"`
+	ClientID       uint      `gorm:"not null;index:idx_cash_outs_client_created,priority:1"`
+	Client         Client    `gorm:"foreignKey:ClientID" json:"-"`
+	CreatedAt      time.Time `gorm:"index:idx_cash_outs_client_created,priority:2"`
+	IdempotencyKey string    `gorm:"size:100;index"`
+	VoidInfo
 }
 
 func (d *Deposit) BeforeCreate(tx *gorm.DB) (err error) {
@@ -87,6 +185,11 @@ func (c *CashOut) BeforeCreate(tx *gorm.DB) (err error) {
 	return nil
 }
 
+func (r *Refund) BeforeCreate(tx *gorm.DB) (err error) {
+	r.ID = uuid.New()
+	return nil
+}
+
 // Hook BeforeCreate: Se ejecuta automáticamente antes de insertar en la DB
 func (t *Transaction) BeforeCreate(tx *gorm.DB) (err error) {
 	// Generamos el UUID desde Go
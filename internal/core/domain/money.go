@@ -0,0 +1,174 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money representa un monto en unidades mínimas (centavos).
+// Usamos enteros para que sumar miles de operaciones no acumule errores de float64.
+type Money int64
+
+// minorUnits es el número de centavos por unidad (MXN, USD, etc. usan 2 decimales)
+const minorUnits = 100
+
+var ErrInvalidMoney = &Error{Code: "INVALID_AMOUNT", Message: "monto inválido"}
+
+// MoneyFromUnits construye un monto a partir de unidades enteras. Ej: MoneyFromUnits(10000) = $10,000.00
+func MoneyFromUnits(units int64) Money {
+	return Money(units * minorUnits)
+}
+
+// ParseMoney convierte un texto decimal ("150", "150.5", "150.50") a Money sin pasar por float64
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, ErrInvalidMoney
+	}
+
+	negative := false
+	switch s[0] {
+	case '-':
+		negative = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	if intPart == "" && fracPart == "" {
+		return 0, ErrInvalidMoney
+	}
+	// Solo dígitos: ParseInt aceptaría otro signo ("++5") o uno en los centavos ("5.+1" serían 5.01)
+	if !isDigits(intPart) || !isDigits(fracPart) {
+		return 0, ErrInvalidMoney
+	}
+	if intPart == "" {
+		intPart = "0"
+	}
+	// Aceptamos ceros de más (Postgres puede regresar "10.000"), pero no centavos fraccionarios
+	if len(fracPart) > 2 {
+		if strings.Trim(fracPart[2:], "0") != "" {
+			return 0, fmt.Errorf("%w: máximo 2 decimales", ErrInvalidMoney)
+		}
+		fracPart = fracPart[:2]
+	}
+	for len(fracPart) < 2 {
+		fracPart += "0"
+	}
+
+	units, err := strconv.ParseInt(intPart, 10, 64)
+	if err != nil || units < 0 {
+		return 0, ErrInvalidMoney
+	}
+	cents, err := strconv.ParseInt(fracPart, 10, 64)
+	if err != nil || cents < 0 {
+		return 0, ErrInvalidMoney
+	}
+	if units > (math.MaxInt64-cents)/minorUnits {
+		return 0, fmt.Errorf("%w: fuera de rango", ErrInvalidMoney)
+	}
+
+	total := Money(units*minorUnits + cents)
+	if negative {
+		total = -total
+	}
+	return total, nil
+}
+
+// isDigits indica si s solo tiene dígitos ASCII; vacío cuenta como válido
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// MustParseMoney es como ParseMoney pero hace panic; útil para constantes y tests
+func MustParseMoney(s string) Money {
+	m, err := ParseMoney(s)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// String regresa el monto con exactamente 2 decimales. Ej: "1500.50"
+func (m Money) String() string {
+	sign := ""
+	v := int64(m)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/minorUnits, v%minorUnits)
+}
+
+// MarshalJSON codifica el monto como string para que ningún cliente lo lea como float
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+// UnmarshalJSON acepta tanto "150.50" como 150.50; el número se parsea como texto, nunca como float
+func (m *Money) UnmarshalJSON(data []byte) error {
+	raw := strings.TrimSpace(string(data))
+	if raw == "null" {
+		return nil
+	}
+	if strings.HasPrefix(raw, `"`) {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		raw = s
+	}
+	parsed, err := ParseMoney(raw)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Value guarda el monto en columnas NUMERIC de Postgres
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan lee columnas NUMERIC (y resultados de SUM) desde Postgres
+func (m *Money) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+		return nil
+	case []byte:
+		parsed, err := ParseMoney(string(v))
+		if err != nil {
+			return err
+		}
+		*m = parsed
+		return nil
+	case string:
+		parsed, err := ParseMoney(v)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+		return nil
+	case int64:
+		*m = MoneyFromUnits(v)
+		return nil
+	case float64:
+		// Solo para columnas legadas en float8; redondeamos al centavo más cercano
+		*m = Money(math.Round(v * minorUnits))
+		return nil
+	default:
+		return fmt.Errorf("no se puede convertir %T a Money", src)
+	}
+}
@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMoney(t *testing.T) {
+	cases := map[string]Money{
+		"150":      15000,
+		"150.5":    15050,
+		"150.50":   15050,
+		"0.01":     1,
+		".99":      99,
+		"-10.00":   -1000,
+		"10.000":   1000, // Postgres puede regresar ceros de más
+		"10000.00": MoneyFromUnits(10000),
+	}
+	for input, expected := range cases {
+		got, err := ParseMoney(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, expected, got, input)
+	}
+
+	// Centavos fraccionarios, texto basura o signos fuera de lugar se rechazan
+	for _, input := range []string{"", "abc", "1.005", "1.2.3", "-", "++5", "-+5", "--5", "5.+1", "5.-0", "5.0-", "1_000", "١٢"} {
+		_, err := ParseMoney(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestMoney_SumHasNoDrift(t *testing.T) {
+	// Con float64, sumar 0.10 mil veces no da exactamente 100
+	var total Money
+	for i := 0; i < 1000; i++ {
+		total += MustParseMoney("0.10")
+	}
+	assert.Equal(t, MoneyFromUnits(100), total)
+}
+
+func TestMoney_JSON(t *testing.T) {
+	out, err := json.Marshal(struct {
+		Amount Money `json:"amount"`
+	}{Amount: MustParseMoney("1500.5")})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"amount":"1500.50"}`, string(out))
+
+	// Aceptamos el monto como string o como número
+	var req struct {
+		Amount Money `json:"amount"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(`{"amount":"99.90"}`), &req))
+	assert.Equal(t, Money(9990), req.Amount)
+	assert.NoError(t, json.Unmarshal([]byte(`{"amount":99.9}`), &req))
+	assert.Equal(t, Money(9990), req.Amount)
+	assert.Error(t, json.Unmarshal([]byte(`{"amount":"99.999"}`), &req))
+}
+
+func TestMoney_Scan(t *testing.T) {
+	var m Money
+	assert.NoError(t, m.Scan([]byte("1234.56")))
+	assert.Equal(t, Money(123456), m)
+	assert.NoError(t, m.Scan(nil))
+	assert.Equal(t, Money(0), m)
+
+	v, err := MustParseMoney("-7.5").Value()
+	assert.NoError(t, err)
+	assert.Equal(t, "-7.50", v)
+}
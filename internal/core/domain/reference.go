@@ -0,0 +1,205 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Algoritmos de dígito verificador; el dígito es siempre el último carácter de la referencia
+const (
+	CheckDigitMod10 = "MOD10" // Luhn
+	CheckDigitMod11 = "MOD11" // Pesos 2..7 de derecha a izquierda; si sale 10 u 11 el dígito es 0
+)
+
+// Conjuntos de caracteres que se pueden usar en ReferenceRule.Charset
+const (
+	CharsetDigits       = "DIGITS"
+	CharsetAlphanumeric = "ALPHANUMERIC" // Letras mayúsculas y dígitos
+)
+
+// ReferenceField es una parte de la referencia: Start es la posición (desde 0) y Length cuántos caracteres
+type ReferenceField struct {
+	Start  int `json:"start"`
+	Length int `json:"length"`
+}
+
+func (f ReferenceField) in(reference string) (string, bool) {
+	if f.Start < 0 || f.Length <= 0 || f.Start+f.Length > len(reference) {
+		return "", false
+	}
+	return reference[f.Start : f.Start+f.Length], true
+}
+
+// ReferenceRule describe cómo es una referencia válida de un biller (código de barras, número de teléfono, etc.)
+type ReferenceRule struct {
+	Name       string          `json:"name"` // Para los mensajes. Ej: "código de barras CFE"
+	MinLength  int             `json:"min_length"`
+	MaxLength  int             `json:"max_length"` // 0 = sin máximo
+	Charset    string          `json:"charset"`    // CharsetDigits, CharsetAlphanumeric o vacío (cualquier carácter visible)
+	CheckDigit string          `json:"check_digit"`
+	Amount     *ReferenceField `json:"amount,omitempty"` // Monto a pagar en centavos
+	Expiry     *ReferenceField `json:"expiry,omitempty"` // Fecha límite AAMMDD; se puede pagar todo ese día
+}
+
+// ReferenceRules es el registro de reglas: gana la del merchant y si no tiene, la de su tipo de servicio
+type ReferenceRules struct {
+	ByMerchant    map[uint]ReferenceRule   `json:"merchants"`
+	ByServiceType map[string]ReferenceRule `json:"service_types"`
+}
+
+// For regresa la regla que aplica al merchant; false si no tiene (basta con que la referencia no esté vacía)
+func (r ReferenceRules) For(merchant *Merchant) (ReferenceRule, bool) {
+	if rule, ok := r.ByMerchant[merchant.ID]; ok {
+		return rule, true
+	}
+	if merchant.ServiceType == "" {
+		return ReferenceRule{}, false
+	}
+	rule, ok := r.ByServiceType[merchant.ServiceType]
+	return rule, ok
+}
+
+// Validate revisa que las reglas tengan sentido; se llama al cargarlas para no rechazar pagos por un error de configuración
+func (r ReferenceRules) Validate() error {
+	for id, rule := range r.ByMerchant {
+		if err := rule.validateConfig(); err != nil {
+			return fmt.Errorf("regla de referencia del merchant %d: %w", id, err)
+		}
+	}
+	for serviceType, rule := range r.ByServiceType {
+		if err := rule.validateConfig(); err != nil {
+			return fmt.Errorf("regla de referencia de %s: %w", serviceType, err)
+		}
+	}
+	return nil
+}
+
+func (r ReferenceRule) validateConfig() error {
+	if r.MinLength < 0 || (r.MaxLength > 0 && r.MaxLength < r.MinLength) {
+		return fmt.Errorf("largo inválido (%d a %d)", r.MinLength, r.MaxLength)
+	}
+	switch r.Charset {
+	case "", CharsetDigits, CharsetAlphanumeric:
+	default:
+		return fmt.Errorf("charset desconocido %q", r.Charset)
+	}
+	switch r.CheckDigit {
+	case "", CheckDigitMod10, CheckDigitMod11:
+	default:
+		return fmt.Errorf("dígito verificador desconocido %q", r.CheckDigit)
+	}
+	return nil
+}
+
+// Validate revisa la referencia contra la regla. amount cero omite revisar el monto incluido
+// (por ejemplo al consultar el recibo, antes de saber cuánto se va a pagar).
+func (r ReferenceRule) Validate(reference string, amount Money, now time.Time) error {
+	name := r.Name
+	if name == "" {
+		name = "referencia"
+	}
+
+	if len(reference) < r.MinLength || (r.MaxLength > 0 && len(reference) > r.MaxLength) {
+		length := fmt.Sprintf("%d", r.MinLength)
+		if r.MaxLength != r.MinLength {
+			length = fmt.Sprintf("entre %d y %d", r.MinLength, r.MaxLength)
+			if r.MaxLength == 0 {
+				length = fmt.Sprintf("al menos %d", r.MinLength)
+			}
+		}
+		return fmt.Errorf("%w: %s debe tener %s caracteres y tiene %d", ErrInvalidReference, name, length, len(reference))
+	}
+	for i, c := range reference {
+		if !inCharset(r.Charset, c) {
+			return fmt.Errorf("%w: %s tiene un carácter no permitido %q en la posición %d", ErrInvalidReference, name, c, i+1)
+		}
+	}
+
+	if r.CheckDigit != "" {
+		if len(reference) < 2 {
+			return fmt.Errorf("%w: %s es demasiado corta para tener dígito verificador", ErrInvalidReference, name)
+		}
+		expected, err := checkDigit(r.CheckDigit, reference[:len(reference)-1])
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidReference, name, err)
+		}
+		if got := reference[len(reference)-1]; got != expected {
+			return fmt.Errorf("%w: %s tiene dígito verificador %c y debería ser %c", ErrInvalidReference, name, got, expected)
+		}
+	}
+
+	if r.Expiry != nil {
+		raw, ok := r.Expiry.in(reference)
+		if !ok {
+			return fmt.Errorf("%w: %s no trae fecha límite", ErrInvalidReference, name)
+		}
+		due, err := time.ParseInLocation("060102", raw, now.Location())
+		if err != nil {
+			return fmt.Errorf("%w: %s trae una fecha límite inválida (%s)", ErrInvalidReference, name, raw)
+		}
+		if !now.Before(due.AddDate(0, 0, 1)) {
+			return fmt.Errorf("%w: venció el %s", ErrReferenceExpired, due.Format(time.DateOnly))
+		}
+	}
+
+	if r.Amount != nil {
+		raw, ok := r.Amount.in(reference)
+		if !ok {
+			return fmt.Errorf("%w: %s no trae monto", ErrInvalidReference, name)
+		}
+		cents, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: %s trae un monto inválido (%s)", ErrInvalidReference, name, raw)
+		}
+		if amount != 0 && Money(cents) != amount {
+			return fmt.Errorf("%w: el recibo es por %s y se quieren pagar %s", ErrReferenceAmountMismatch, Money(cents), amount)
+		}
+	}
+	return nil
+}
+
+func inCharset(charset string, c rune) bool {
+	switch charset {
+	case CharsetDigits:
+		return c >= '0' && c <= '9'
+	case CharsetAlphanumeric:
+		return (c >= '0' && c <= '9') || (c >= 'A' && c <= 'Z')
+	default:
+		return c > ' ' && c != 0x7f
+	}
+}
+
+// checkDigit calcula el dígito verificador de body (la referencia sin su último carácter)
+func checkDigit(algorithm string, body string) (byte, error) {
+	sum := 0
+	for i := 0; i < len(body); i++ {
+		c := body[len(body)-1-i] // De derecha a izquierda
+		if c < '0' || c > '9' {
+			return 0, fmt.Errorf("el dígito verificador solo se calcula sobre dígitos")
+		}
+		d := int(c - '0')
+		switch algorithm {
+		case CheckDigitMod10:
+			if i%2 == 0 {
+				if d *= 2; d > 9 {
+					d -= 9
+				}
+			}
+			sum += d
+		case CheckDigitMod11:
+			sum += d * (2 + i%6)
+		default:
+			return 0, fmt.Errorf("algoritmo de dígito verificador desconocido %q", algorithm)
+		}
+	}
+
+	if algorithm == CheckDigitMod10 {
+		return byte('0' + (10-sum%10)%10), nil
+	}
+	dv := 11 - sum%11
+	if dv >= 10 {
+		dv = 0
+	}
+	return byte('0' + dv), nil
+}
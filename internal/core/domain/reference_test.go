@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// withCheckDigit agrega el dígito verificador a body
+func withCheckDigit(t *testing.T, algorithm, body string) string {
+	d, err := checkDigit(algorithm, body)
+	assert.NoError(t, err)
+	return body + string(d)
+}
+
+func TestCheckDigit_KnownValues(t *testing.T) {
+	d, err := checkDigit(CheckDigitMod10, "7992739871")
+	assert.NoError(t, err)
+	assert.Equal(t, byte('3'), d) // 79927398713 es el ejemplo clásico de Luhn
+
+	d, err = checkDigit(CheckDigitMod11, "261533")
+	assert.NoError(t, err)
+	assert.Equal(t, byte('9'), d) // 3·2 + 3·3 + 5·4 + 1·5 + 6·6 + 2·7 = 90; 11 - 90%11 = 9
+}
+
+func TestReferenceRule_Validate(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	// Código de barras: 12 de servicio + fecha límite AAMMDD + monto en centavos (11) + dígito verificador
+	barcode := ReferenceRule{
+		Name: "código de barras", MinLength: 30, MaxLength: 30, Charset: CharsetDigits, CheckDigit: CheckDigitMod10,
+		Expiry: &ReferenceField{Start: 12, Length: 6}, Amount: &ReferenceField{Start: 18, Length: 11},
+	}
+	valid := withCheckDigit(t, CheckDigitMod10, "123456789012"+"261018"+"00000084550")
+	phone := ReferenceRule{Name: "número de teléfono", MinLength: 10, MaxLength: 10, Charset: CharsetDigits}
+
+	assert.NoError(t, barcode.Validate(valid, MustParseMoney("845.50"), now))
+	assert.NoError(t, barcode.Validate(valid, 0, now), "sin monto no se compara")
+	assert.NoError(t, phone.Validate("5512345678", 0, now))
+	assert.NoError(t, ReferenceRule{}.Validate("CFE-123", 0, now), "sin regla basta con que no esté vacía")
+
+	for name, tc := range map[string]struct {
+		rule      ReferenceRule
+		reference string
+		amount    Money
+		err       error
+		message   string
+	}{
+		"muy corta":            {phone, "551234567", 0, ErrInvalidReference, "debe tener 10 caracteres y tiene 9"},
+		"letras en teléfono":   {phone, "55123456O8", 0, ErrInvalidReference, "carácter no permitido 'O' en la posición 9"},
+		"dígito verificador":   {barcode, valid[:29] + string('0'+(valid[29]-'0'+1)%10), 0, ErrInvalidReference, "dígito verificador"},
+		"vencido":              {barcode, withCheckDigit(t, CheckDigitMod10, "123456789012"+"261017"+"00000084550"), 0, ErrReferenceExpired, "venció el 2026-10-17"},
+		"fecha inválida":       {barcode, withCheckDigit(t, CheckDigitMod10, "123456789012"+"261399"+"00000084550"), 0, ErrInvalidReference, "fecha límite inválida"},
+		"monto distinto":       {barcode, valid, MustParseMoney("800"), ErrReferenceAmountMismatch, "el recibo es por 845.50 y se quieren pagar 800.00"},
+		"alfanumérico":         {ReferenceRule{Charset: CharsetAlphanumeric}, "abc", 0, ErrInvalidReference, "carácter no permitido 'a'"},
+		"espacio sin charset":  {ReferenceRule{}, "CFE 123", 0, ErrInvalidReference, "carácter no permitido ' '"},
+		"rango de largo":       {ReferenceRule{MinLength: 6, MaxLength: 20}, "ABC", 0, ErrInvalidReference, "entre 6 y 20"},
+		"verificador en letra": {ReferenceRule{CheckDigit: CheckDigitMod11}, "12A45", 0, ErrInvalidReference, "solo se calcula sobre dígitos"},
+	} {
+		err := tc.rule.Validate(tc.reference, tc.amount, now)
+		assert.ErrorIs(t, err, tc.err, name)
+		if err != nil {
+			assert.Contains(t, err.Error(), tc.message, name)
+		}
+	}
+}
+
+func TestReferenceRules_ForAndValidate(t *testing.T) {
+	rules := ReferenceRules{
+		ByMerchant:    map[uint]ReferenceRule{7: {Name: "contrato"}},
+		ByServiceType: map[string]ReferenceRule{"TELEPHONE": {Name: "número de teléfono"}},
+	}
+
+	rule, ok := rules.For(&Merchant{ID: 7, ServiceType: "TELEPHONE"})
+	assert.True(t, ok)
+	assert.Equal(t, "contrato", rule.Name, "la regla del merchant gana a la de su tipo de servicio")
+	rule, ok = rules.For(&Merchant{ID: 8, ServiceType: "TELEPHONE"})
+	assert.True(t, ok)
+	assert.Equal(t, "número de teléfono", rule.Name)
+	_, ok = rules.For(&Merchant{ID: 9})
+	assert.False(t, ok)
+
+	assert.NoError(t, rules.Validate())
+	assert.Error(t, ReferenceRules{ByServiceType: map[string]ReferenceRule{"WATER": {CheckDigit: "mod10"}}}.Validate())
+	assert.Error(t, ReferenceRules{ByMerchant: map[uint]ReferenceRule{1: {MinLength: 10, MaxLength: 5}}}.Validate())
+}
@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Balance es el saldo del cliente en una moneda.
+// Ledger es lo que dice el libro mayor; Available descuenta las retenciones de pagos autorizados.
+type Balance struct {
+	Currency  string `json:"currency"`
+	Ledger    Money  `json:"ledger_balance"`
+	Held      Money  `json:"held"`
+	Available Money  `json:"available_balance"`
+}
+
+// StatementQuery pide el estado de cuenta de un cliente en una moneda, del más antiguo al más reciente
+type StatementQuery struct {
+	ClientID uint
+	Currency string
+	From     *time.Time // Inclusivo
+	To       *time.Time // Exclusivo
+	After    *PageCursor
+	Limit    int
+}
+
+// Validate revisa el rango de fechas y el tamaño de página
+func (q StatementQuery) Validate() error {
+	if q.From != nil && q.To != nil && !q.From.Before(*q.To) {
+		return fmt.Errorf("%w: from debe ser anterior a to", ErrInvalidFilter)
+	}
+	if q.Limit < 0 || q.Limit > MaxPageSize {
+		return fmt.Errorf("%w: el tamaño de página debe estar entre 1 y %d", ErrInvalidFilter, MaxPageSize)
+	}
+	return nil
+}
+
+// StatementLine es una póliza vista desde la cuenta del cliente: depósitos, pagos, retiros, devoluciones y anulaciones.
+// Amount es el efecto neto en el saldo (comisiones incluidas): positivo si abona, negativo si carga.
+type StatementLine struct {
+	EntryID       uuid.UUID `json:"entry_id"`
+	OperationType string    `json:"operation_type"` // DEPOSIT, PAYMENT, CASHOUT, REFUND, VOID
+	OperationID   uuid.UUID `json:"operation_id"`
+	Description   string    `json:"description"`
+	Amount        Money     `json:"amount"`
+	Balance       Money     `json:"balance"` // Saldo después de esta línea
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Statement es una página del estado de cuenta. OpeningBalance es el saldo antes de la primera línea
+// y ClosingBalance el saldo después de la última.
+type Statement struct {
+	Currency       string          `json:"currency"`
+	OpeningBalance Money           `json:"opening_balance"`
+	ClosingBalance Money           `json:"closing_balance"`
+	Lines          []StatementLine `json:"lines"`
+	NextCursor     string          `json:"next_cursor,omitempty"`
+}
+
+// Cursor regresa la posición de la línea para pedir la página siguiente
+func (l StatementLine) Cursor() PageCursor {
+	return PageCursor{CreatedAt: l.CreatedAt, ID: l.EntryID}
+}
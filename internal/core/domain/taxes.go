@@ -0,0 +1,29 @@
+package domain
+
+import "time"
+
+// Regiones fiscales de los clientes
+const (
+	RegionGeneral    = "GENERAL"    // IVA 16%
+	RegionFronteriza = "FRONTERIZA" // Región fronteriza norte/sur, IVA 8%
+)
+
+// TaxAccountCode es la cuenta donde se acumula el IVA trasladado que se le debe al SAT
+const TaxAccountCode = "TAX:IVA"
+
+// TaxRate es la tasa de IVA configurada para una región
+type TaxRate struct {
+	ID        uint   `gorm:"primaryKey"`
+	Region    string `gorm:"size:20;uniqueIndex;not null"`
+	Name      string `gorm:"size:20;not null;default:'IVA'"`
+	RateBps   int64  `gorm:"not null"` // 1600 = 16%
+	CreatedAt time.Time
+}
+
+// Apply calcula el desglose de impuesto sobre la comisión: base, tasa y monto
+func (r TaxRate) Apply(f *Fee) {
+	f.TaxName = r.Name
+	f.TaxBase = f.Amount
+	f.TaxRateBps = r.RateBps
+	f.TaxAmount = ApplyRate(f.Amount, r.RateBps)
+}
@@ -1,32 +1,198 @@
 package ports
 
 import (
+	"time"
+
+	"github.com/google/uuid"
 	"github.com/scorazag/gopayhub/internal/core/domain"
 )
 
+// IdempotencyRepository guarda las respuestas ya enviadas para repetirlas en los reintentos
+type IdempotencyRepository interface {
+	GetIdempotencyKey(scope domain.IdempotencyScope) (*domain.IdempotencyKey, error)
+	// ClaimIdempotencyKey inserta la llave solo si no existe (o ya venció); false si alguien más ya la tiene
+	ClaimIdempotencyKey(key *domain.IdempotencyKey) (bool, error)
+	// SaveIdempotencyKey guarda la respuesta de una llave ya apartada
+	SaveIdempotencyKey(key *domain.IdempotencyKey) error
+	// DeleteIdempotencyKey libera una llave apartada cuya petición falló
+	DeleteIdempotencyKey(scope domain.IdempotencyScope) error
+	// PurgeExpiredIdempotencyKeys borra las llaves vencidas y regresa cuántas borró
+	PurgeExpiredIdempotencyKeys(now time.Time) (int64, error)
+}
+
 // PaymentRepository define qué puede hacer la base de datos
 type PaymentRepository interface {
+	IdempotencyRepository
 	GetClientByApiKey(apiKey string) (*domain.Client, error)
+	GetClientByID(id uint) (*domain.Client, error)
+	// GetTaxRate regresa la tasa de IVA de la región; error si no está configurada
+	GetTaxRate(region string) (*domain.TaxRate, error)
 	GetMerchantByID(id uint) (*domain.Merchant, error)
 	CreateTransaction(tx *domain.Transaction) error
+	// GetTransactionForUpdate bloquea la transacción hasta que termine Atomic
+	GetTransactionForUpdate(id uuid.UUID) (*domain.Transaction, error)
+	SaveTransaction(tx *domain.Transaction) error
+	CreateRefund(refund *domain.Refund) error
+	SaveRefund(refund *domain.Refund) error
+	// GetRefund regresa la devolución; domain.ErrNotFound si no existe
+	GetRefund(id uuid.UUID) (*domain.Refund, error)
+	GetDepositForUpdate(id uuid.UUID) (*domain.Deposit, error)
+	SaveDeposit(deposit *domain.Deposit) error
+	GetCashOutForUpdate(id uuid.UUID) (*domain.CashOut, error)
+	SaveCashOut(cashout *domain.CashOut) error
 	CreateDeposit(tx *domain.Deposit) error
-	GetIdempotencyKey(key string) (*domain.IdempotencyKey, error)
-	SaveIdempotencyKey(key *domain.IdempotencyKey) error
-	GetClientBalance(clientID uint) (float64, error) // <--- El nuevo superpoder
+	GetClientBalance(clientID uint, currency string) (domain.Money, error) // Se lee del libro mayor, por moneda
+	// GetClientHolds suma las retenciones AUTHORIZED vigentes; no están en el libro mayor todavía
+	GetClientHolds(clientID uint, currency string) (domain.Money, error)
+	// GetClientBalanceBefore es el saldo con las pólizas anteriores a esa posición (sin incluirla)
+	GetClientBalanceBefore(clientID uint, currency string, at domain.PageCursor) (domain.Money, error)
+	// ListStatementLines regresa el efecto de cada póliza en la cuenta del cliente, del más antiguo al más reciente
+	ListStatementLines(q domain.StatementQuery) ([]domain.StatementLine, error)
+	// ReleaseExpiredHolds pasa a RELEASED las retenciones vencidas y regresa cuántas liberó
+	ReleaseExpiredHolds(now time.Time) (int64, error)
 	CreateCashOut(cashout *domain.CashOut) error
+	PostJournalEntry(entry *domain.JournalEntry) error
+	// FindFeeSchedules regresa los esquemas activos que aplican (los campos vacíos son comodín)
+	FindFeeSchedules(operationType string, currency string, clientID uint, merchantID uint, serviceType string) ([]domain.FeeSchedule, error)
+	// FindLimits regresa los límites activos que aplican a la operación (los campos vacíos son comodín)
+	FindLimits(op domain.LimitedOperation) ([]domain.Limit, error)
+	// SumOperations suma el monto de las operaciones vigentes (no anuladas ni fallidas) que cumplen la consulta
+	SumOperations(q domain.LimitUsageQuery) (domain.Money, error)
+	// LockLimit bloquea el límite hasta que termine la transacción, para que los límites compartidos
+	// no los lean al mismo tiempo dos clientes distintos (usar dentro de Atomic)
+	LockLimit(id uint) error
+	// LockClientAccount bloquea la cuenta del cliente en esa moneda hasta que termine la transacción (usar dentro de Atomic)
+	LockClientAccount(clientID uint, currency string) error
+	// Consultas de una operación del cliente; domain.ErrNotFound si no existe o es de otro cliente
+	GetTransaction(id uuid.UUID, clientID uint) (*domain.Transaction, error)
+	GetDeposit(id uuid.UUID, clientID uint) (*domain.Deposit, error)
+	GetCashOut(id uuid.UUID, clientID uint) (*domain.CashOut, error)
+	// Búsqueda por la llave de idempotencia con que se creó; si se repitió, la más reciente
+	GetTransactionByIdempotencyKey(key string, clientID uint) (*domain.Transaction, error)
+	GetDepositByIdempotencyKey(key string, clientID uint) (*domain.Deposit, error)
+	GetCashOutByIdempotencyKey(key string, clientID uint) (*domain.CashOut, error)
+	// Listados del cliente de f.ClientID, del más reciente al más antiguo; regresan a lo más f.Limit registros
+	ListTransactions(f domain.OperationFilter) ([]domain.Transaction, error)
+	ListDeposits(f domain.OperationFilter) ([]domain.Deposit, error)
+	ListCashOuts(f domain.OperationFilter) ([]domain.CashOut, error)
+	// Cola persistente de pagos asíncronos
+	EnqueuePaymentJob(job *domain.PaymentJob) error
+	// ClaimPaymentJob aparta hasta now+lease el siguiente job listo (RunAt vencido y sin worker); nil si no hay
+	ClaimPaymentJob(now time.Time, lease time.Duration) (*domain.PaymentJob, error)
+	SavePaymentJob(job *domain.PaymentJob) error
+	DeletePaymentJob(id uint) error
+	// Cola de devoluciones que el biller no contestó; se aparta igual que la de pagos
+	EnqueueRefundJob(job *domain.RefundJob) error
+	ClaimRefundJob(now time.Time, lease time.Duration) (*domain.RefundJob, error)
+	SaveRefundJob(job *domain.RefundJob) error
+	DeleteRefundJob(id uint) error
+	// Atomic ejecuta fn dentro de una transacción de BD; si fn regresa error, nada se guarda
+	Atomic(fn func(repo PaymentRepository) error) error
 }
 
 // PaymentService define qué lógica de negocio exponemos
 type PaymentService interface {
-	ProcessPayment(amount float64, merchantID uint, clientID uint, reference string, idemKey string) (*domain.Transaction, error)
+	// billInquiryID liga el pago a una consulta del recibo (uuid.Nil si no hubo); sin monto se paga lo que se debe
+	ProcessPayment(amount domain.Money, currency string, merchantID uint, clientID uint, reference string, billInquiryID uuid.UUID, idemKey string) (*domain.Transaction, error)
+	// ProcessPaymentAsync cobra y encola el aviso al biller; regresa la transacción en PENDING
+	ProcessPaymentAsync(amount domain.Money, currency string, merchantID uint, clientID uint, reference string, billInquiryID uuid.UUID, idemKey string) (*domain.Transaction, error)
+	// ProcessQueuedPayment confirma con el biller el siguiente pago encolado; false si la cola estaba vacía
+	ProcessQueuedPayment() (bool, error)
+}
+
+// BillService - Consulta de recibos pendientes antes de cobrar
+type BillService interface {
+	InquireBill(merchantID uint, clientID uint, reference string) (*domain.Bill, error)
 }
 
 // DepositService - Contrato exclusivo para depósitos
 type DepositService interface {
-	ProcessDeposit(amount float64, merchantID uint, clientID uint, reference string, idemKey string) (*domain.Deposit, error)
+	ProcessDeposit(amount domain.Money, currency string, merchantID uint, clientID uint, reference string, storeName string, idemKey string) (*domain.Deposit, error)
 }
 
 // CashOutService - Contrato exclusivo para retiros
 type CashOutService interface {
-	ProcessCashOut(amount float64, merchantID uint, clientID uint, reference string, idemKey string) (*domain.CashOut, error)
+	ProcessCashOut(amount domain.Money, currency string, merchantID uint, clientID uint, reference string, storeName string, idemKey string) (*domain.CashOut, error)
+}
+
+// AuthorizationService - Contrato para pagos en dos fases (retener y luego capturar o liberar)
+type AuthorizationService interface {
+	AuthorizePayment(amount domain.Money, currency string, merchantID uint, clientID uint, reference string, idemKey string) (*domain.Transaction, error)
+	CapturePayment(transactionID uuid.UUID, clientID uint) (*domain.Transaction, error)
+	ReleasePayment(transactionID uuid.UUID, clientID uint) (*domain.Transaction, error)
+	ExpireHolds() (int64, error)
+}
+
+// RefundService - Contrato para devoluciones de pagos completados
+type RefundService interface {
+	// Si amount es cero se devuelve todo lo que resta de la transacción. Si el biller no contesta
+	// la devolución se regresa en PENDING y la termina la cola.
+	RefundPayment(transactionID uuid.UUID, amount domain.Money, clientID uint, reason string, idemKey string) (*domain.Refund, error)
+	// ProcessQueuedRefund le vuelve a preguntar al biller por la siguiente devolución encolada; false si la cola estaba vacía
+	ProcessQueuedRefund() (bool, error)
+}
+
+// VoidService - Contrato para anular depósitos y retiros capturados por error
+type VoidService interface {
+	VoidDeposit(id uuid.UUID, clientID uint, voidedBy string, reason string) (*domain.Deposit, error)
+	VoidCashOut(id uuid.UUID, clientID uint, voidedBy string, reason string) (*domain.CashOut, error)
+}
+
+// QueryService - Consulta de operaciones del cliente autenticado; nunca regresa las de otro cliente
+type QueryService interface {
+	GetTransaction(id uuid.UUID, clientID uint) (*domain.Transaction, error)
+	GetDeposit(id uuid.UUID, clientID uint) (*domain.Deposit, error)
+	GetCashOut(id uuid.UUID, clientID uint) (*domain.CashOut, error)
+	GetTransactionByIdempotencyKey(key string, clientID uint) (*domain.Transaction, error)
+	GetDepositByIdempotencyKey(key string, clientID uint) (*domain.Deposit, error)
+	GetCashOutByIdempotencyKey(key string, clientID uint) (*domain.CashOut, error)
+	ListTransactions(f domain.OperationFilter) (*domain.Page[domain.Transaction], error)
+	ListDeposits(f domain.OperationFilter) (*domain.Page[domain.Deposit], error)
+	ListCashOuts(f domain.OperationFilter) (*domain.Page[domain.CashOut], error)
+}
+
+// AccountService - Saldo y estado de cuenta del cliente
+type AccountService interface {
+	GetBalance(clientID uint, currency string) (*domain.Balance, error)
+	GetStatement(q domain.StatementQuery) (*domain.Statement, error)
+}
+
+// MerchantConnector es el puerto hacia los sistemas de cada biller (CFE, Netflix, etc.)
+type MerchantConnector interface {
+	// NotifyPayment le avisa al biller de un pago. Un rechazo regresa Approved=false;
+	// un error es que no obtuvimos respuesta (red, timeout, 5xx).
+	NotifyPayment(merchant *domain.Merchant, tx *domain.Transaction) (*domain.PaymentConfirmation, error)
+	// NotifyRefund le avisa al biller de una devolución. Un rechazo es domain.ErrRefundRejected;
+	// cualquier otro error es que no obtuvimos respuesta y el biller pudo haberla aplicado.
+	NotifyRefund(merchant *domain.Merchant, tx *domain.Transaction, refund *domain.Refund) error
+	// InquireBill consulta el recibo pendiente de la referencia; domain.ErrBillNotFound si no hay
+	InquireBill(merchant *domain.Merchant, reference string) (*domain.Bill, error)
+}
+
+// MerchantCircuits es el circuit breaker por biller que envuelve al MerchantConnector
+type MerchantCircuits interface {
+	// Available regresa domain.ErrMerchantUnavailable si el circuito del biller está abierto,
+	// para rechazar el pago antes de cobrarle al cliente
+	Available(merchant *domain.Merchant) error
+	// Circuits regresa el estado de cada biller al que ya se le ha llamado
+	Circuits() []domain.CircuitState
+}
+
+// BillCache guarda por poco tiempo las consultas de recibos de cada cliente; un recibo vencido (ExpiresAt) ya no se regresa
+type BillCache interface {
+	// Take saca la consulta para ligarla a un pago, así no sirve para cobrar otra vez; solo la encuentra el cliente que la hizo
+	Take(inquiryID uuid.UUID, clientID uint) (*domain.Bill, bool)
+	// Find busca la consulta vigente que hizo el cliente de esa referencia para no volver a preguntarle al biller
+	Find(clientID uint, merchantID uint, reference string) (*domain.Bill, bool)
+	Put(bill *domain.Bill)
+}
+
+// ClientNotifier le avisa al cliente que terminó un pago asíncrono (COMPLETED o FAILED)
+type ClientNotifier interface {
+	NotifyTransaction(client *domain.Client, tx *domain.Transaction) error
+}
+
+// FXRateProvider da el tipo de cambio vigente entre dos monedas
+type FXRateProvider interface {
+	GetRate(from string, to string) (*domain.FXQuote, error)
 }
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"github.com/google/uuid"
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/scorazag/gopayhub/internal/core/ports"
+)
+
+type accountService struct {
+	repo ports.PaymentRepository
+}
+
+func NewAccountService(repo ports.PaymentRepository) ports.AccountService {
+	return &accountService{repo: repo}
+}
+
+func (s *accountService) GetBalance(clientID uint, currency string) (*domain.Balance, error) {
+	currency, err := domain.NormalizeCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+	ledger, err := s.repo.GetClientBalance(clientID, currency)
+	if err != nil {
+		return nil, err
+	}
+	held, err := s.repo.GetClientHolds(clientID, currency)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.Balance{Currency: currency, Ledger: ledger, Held: held, Available: ledger - held}, nil
+}
+
+// GetStatement arma una página del estado de cuenta. El saldo inicial se calcula con todas las pólizas
+// anteriores a la primera línea, así que cada página trae su saldo corrido sin depender de las anteriores.
+func (s *accountService) GetStatement(q domain.StatementQuery) (*domain.Statement, error) {
+	currency, err := domain.NormalizeCurrency(q.Currency)
+	if err != nil {
+		return nil, err
+	}
+	q.Currency = currency
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	pageSize := q.Limit
+	if pageSize == 0 {
+		pageSize = domain.DefaultPageSize
+	}
+	q.Limit = pageSize + 1
+
+	lines, err := s.repo.ListStatementLines(q)
+	if err != nil {
+		return nil, err
+	}
+	statement := &domain.Statement{Currency: currency, Lines: lines}
+	if len(lines) > pageSize {
+		statement.Lines = lines[:pageSize]
+		statement.NextCursor = statement.Lines[pageSize-1].Cursor().Encode()
+	}
+
+	opening, err := s.openingBalance(q, statement.Lines)
+	if err != nil {
+		return nil, err
+	}
+	statement.OpeningBalance = opening
+	running := opening
+	for i := range statement.Lines {
+		running += statement.Lines[i].Amount
+		statement.Lines[i].Balance = running
+	}
+	statement.ClosingBalance = running
+	if statement.Lines == nil {
+		statement.Lines = []domain.StatementLine{}
+	}
+	return statement, nil
+}
+
+// openingBalance es el saldo antes de la primera línea. Una página vacía no tiene movimientos,
+// así que su saldo es el del final del rango (o el actual si el rango no tiene fin).
+func (s *accountService) openingBalance(q domain.StatementQuery, lines []domain.StatementLine) (domain.Money, error) {
+	switch {
+	case len(lines) > 0:
+		return s.repo.GetClientBalanceBefore(q.ClientID, q.Currency, lines[0].Cursor())
+	case q.To != nil:
+		return s.repo.GetClientBalanceBefore(q.ClientID, q.Currency, domain.PageCursor{CreatedAt: *q.To, ID: uuid.Nil})
+	default:
+		return s.repo.GetClientBalance(q.ClientID, q.Currency)
+	}
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetBalance_AvailableExcludesHolds(t *testing.T) {
+	mockRepo := new(MockRepo)
+	service := NewAccountService(mockRepo)
+	mockRepo.On("GetClientBalance", uint(1), "MXN").Return(domain.MustParseMoney("1000.00"), nil)
+	mockRepo.On("GetClientHolds", uint(1), "MXN").Return(domain.MustParseMoney("250.00"), nil)
+
+	balance, err := service.GetBalance(1, "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "MXN", balance.Currency)
+	assert.Equal(t, domain.MustParseMoney("1000.00"), balance.Ledger)
+	assert.Equal(t, domain.MustParseMoney("750.00"), balance.Available)
+}
+
+func TestGetStatement_RunningBalance(t *testing.T) {
+	mockRepo := new(MockRepo)
+	service := NewAccountService(mockRepo)
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	lines := []domain.StatementLine{
+		{EntryID: uuid.New(), OperationType: domain.OperationDeposit, Amount: domain.MustParseMoney("500.00"), CreatedAt: start},
+		{EntryID: uuid.New(), OperationType: domain.OperationPayment, Amount: domain.MustParseMoney("-150.00"), CreatedAt: start.Add(time.Hour)},
+		{EntryID: uuid.New(), OperationType: domain.OperationCashOut, Amount: domain.MustParseMoney("-100.00"), CreatedAt: start.Add(2 * time.Hour)},
+	}
+	// Se pide una línea de más para saber si hay otra página
+	mockRepo.On("ListStatementLines", mock.MatchedBy(func(q domain.StatementQuery) bool {
+		return q.ClientID == 1 && q.Currency == "MXN" && q.Limit == 3
+	})).Return(lines, nil)
+	// El saldo inicial sale de todo lo anterior a la primera línea
+	mockRepo.On("GetClientBalanceBefore", uint(1), "MXN", lines[0].Cursor()).Return(domain.MustParseMoney("200.00"), nil)
+
+	statement, err := service.GetStatement(domain.StatementQuery{ClientID: 1, Limit: 2})
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.MustParseMoney("200.00"), statement.OpeningBalance)
+	assert.Len(t, statement.Lines, 2)
+	assert.Equal(t, domain.MustParseMoney("700.00"), statement.Lines[0].Balance)
+	assert.Equal(t, domain.MustParseMoney("550.00"), statement.Lines[1].Balance)
+	assert.Equal(t, domain.MustParseMoney("550.00"), statement.ClosingBalance)
+	assert.Equal(t, lines[1].Cursor().Encode(), statement.NextCursor)
+}
+
+func TestGetStatement_EmptyRangeUsesBalanceAtEnd(t *testing.T) {
+	mockRepo := new(MockRepo)
+	service := NewAccountService(mockRepo)
+	to := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	mockRepo.On("ListStatementLines", mock.Anything).Return([]domain.StatementLine(nil), nil)
+	mockRepo.On("GetClientBalanceBefore", uint(1), "MXN", domain.PageCursor{CreatedAt: to}).Return(domain.MustParseMoney("80.00"), nil)
+
+	statement, err := service.GetStatement(domain.StatementQuery{ClientID: 1, To: &to})
+
+	assert.NoError(t, err)
+	assert.Empty(t, statement.Lines)
+	assert.Equal(t, domain.MustParseMoney("80.00"), statement.OpeningBalance)
+	assert.Equal(t, domain.MustParseMoney("80.00"), statement.ClosingBalance)
+	assert.Empty(t, statement.NextCursor)
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/scorazag/gopayhub/internal/core/ports"
+)
+
+type authorizationService struct {
+	repo       ports.PaymentRepository
+	fx         ports.FXRateProvider
+	limits     *LimitChecker
+	holdTTL    time.Duration // Cuánto vive una retención sin capturar
+	now        func() time.Time
+	references *ReferenceValidator
+	payments   *paymentService // Al capturar, el pago se le avisa al biller igual que un pago directo
+}
+
+func NewAuthorizationService(repo ports.PaymentRepository, fx ports.FXRateProvider, limits *LimitChecker, holdTTL time.Duration, references *ReferenceValidator, connector ports.MerchantConnector, circuits ports.MerchantCircuits) ports.AuthorizationService {
+	payments := &paymentService{repo: repo, connector: connector, circuits: circuits, now: time.Now}
+	return &authorizationService{repo: repo, fx: fx, limits: limits, holdTTL: holdTTL, now: time.Now, references: references, payments: payments}
+}
+
+// AuthorizePayment retiene el monto: baja el saldo disponible pero no genera póliza
+func (s *authorizationService) AuthorizePayment(amount domain.Money, currency string, merchantID uint, clientID uint, reference string, idemKey string) (*domain.Transaction, error) {
+	// 1. REGLAS DE NEGOCIO
+	if amount <= 0 {
+		return nil, domain.ErrInvalidAmount
+	}
+	currency, err := domain.NormalizeCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+
+	merchant, err := s.repo.GetMerchantByID(merchantID)
+	if err != nil {
+		return nil, notFoundAs(err, domain.ErrMerchantNotFound)
+	}
+	// La comisión y el tipo de cambio se fijan al retener; se cobran al capturar
+	conversion, err := convertForMerchant(s.fx, amount, currency, merchant)
+	if err != nil {
+		return nil, err
+	}
+	// La referencia trae el monto en la moneda del merchant
+	if err := s.references.Validate(merchant, reference, merchantAmount(amount, conversion)); err != nil {
+		return nil, err
+	}
+	// Si el biller está caído no tiene caso retenerle el saldo al cliente
+	if err := s.payments.available(merchant); err != nil {
+		return nil, err
+	}
+	fees, err := calculateFees(s.repo, domain.OperationPayment, currency, clientID, merchant, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := s.now().Add(s.holdTTL)
+	tx := &domain.Transaction{
+		Amount:         amount,
+		Currency:       currency,
+		FeeAmount:      domain.TotalFees(fees),
+		Fees:           fees,
+		FXConversion:   conversion,
+		MerchantID:     merchant.ID,
+		ClientID:       clientID,
+		Reference:      reference,
+		Status:         "AUTHORIZED",
+		ExpiresAt:      &expiresAt,
+		IdempotencyKey: idemKey,
+	}
+
+	// 2. Validar saldo disponible y crear la retención bajo el mismo bloqueo que usan los retiros
+	err = s.repo.Atomic(func(repo ports.PaymentRepository) error {
+		if err := repo.LockClientAccount(clientID, currency); err != nil {
+			return err
+		}
+		available, err := availableBalance(repo, clientID, currency)
+		if err != nil {
+			return err
+		}
+		if amount+tx.FeeAmount > available {
+			return domain.ErrInsufficientFunds
+		}
+		// Una retención cuenta para los límites de pagos desde que se autoriza
+		if err := s.limits.Check(repo, paymentLimitOperation(tx)); err != nil {
+			return err
+		}
+		return repo.CreateTransaction(tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+// CapturePayment confirma la retención: se genera la póliza del pago y se le avisa al biller.
+// Desde aquí es un pago como cualquier otro: PENDING hasta que el biller conteste, COMPLETED si lo
+// aplica y FAILED (con el cargo reversado) si lo rechaza; si no contesta, lo termina la cola.
+func (s *authorizationService) CapturePayment(transactionID uuid.UUID, clientID uint) (*domain.Transaction, error) {
+	var tx *domain.Transaction
+	var merchant *domain.Merchant
+	var job *domain.PaymentJob
+	err := s.repo.Atomic(func(repo ports.PaymentRepository) error {
+		var err error
+		tx, err = s.getHold(repo, transactionID, clientID)
+		if err != nil {
+			return err
+		}
+		merchant, err = repo.GetMerchantByID(tx.MerchantID)
+		if err != nil {
+			return notFoundAs(err, domain.ErrMerchantNotFound)
+		}
+		// Con el biller caído la retención sigue vigente y se puede capturar más tarde
+		if err := s.payments.available(merchant); err != nil {
+			return err
+		}
+		tx.Status = "PENDING"
+		tx.ExpiresAt = nil
+		if err := repo.SaveTransaction(tx); err != nil {
+			return err
+		}
+		if err := repo.PostJournalEntry(domain.NewPaymentEntry(tx)); err != nil {
+			return err
+		}
+		job = s.payments.leasedJob(clientID)
+		job.TransactionID = tx.ID
+		return repo.EnqueuePaymentJob(job)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.payments.confirm(tx, merchant, job)
+}
+
+// ReleasePayment cancela la retención y el monto vuelve al saldo disponible
+func (s *authorizationService) ReleasePayment(transactionID uuid.UUID, clientID uint) (*domain.Transaction, error) {
+	var tx *domain.Transaction
+	err := s.repo.Atomic(func(repo ports.PaymentRepository) error {
+		var err error
+		tx, err = s.getHold(repo, transactionID, clientID)
+		if err != nil {
+			return err
+		}
+		tx.Status = "RELEASED"
+		tx.ExpiresAt = nil
+		return repo.SaveTransaction(tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// ExpireHolds libera las retenciones que nadie capturó a tiempo; lo llama un job periódico
+func (s *authorizationService) ExpireHolds() (int64, error) {
+	return s.repo.ReleaseExpiredHolds(s.now())
+}
+
+// getHold bloquea la transacción y revisa que sea una retención vigente del cliente
+func (s *authorizationService) getHold(repo ports.PaymentRepository, transactionID uuid.UUID, clientID uint) (*domain.Transaction, error) {
+	tx, err := repo.GetTransactionForUpdate(transactionID)
+	if err != nil {
+		return nil, notFoundAs(err, domain.ErrTransactionNotFound)
+	}
+	if tx.ClientID != clientID {
+		return nil, domain.ErrTransactionNotFound
+	}
+	if tx.Status != "AUTHORIZED" {
+		return nil, domain.ErrNoActiveHold
+	}
+	if tx.ExpiresAt != nil && !s.now().Before(*tx.ExpiresAt) {
+		return nil, domain.ErrHoldExpired
+	}
+	return tx, nil
+}
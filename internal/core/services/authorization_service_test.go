@@ -0,0 +1,183 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestAuthorizationService(repo *MockRepo, now time.Time) *authorizationService {
+	s := NewAuthorizationService(repo, nil, NewLimitChecker(time.UTC), 15*time.Minute, nil, approvingConnector(), nil).(*authorizationService)
+	s.now = func() time.Time { return now }
+	s.payments.now = s.now
+	return s
+}
+
+func TestAuthorizePayment_HoldsAvailableBalance(t *testing.T) {
+	mockRepo := new(MockRepo)
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	service := newTestAuthorizationService(mockRepo, now)
+
+	mockRepo.On("GetMerchantByID", uint(1)).Return(&domain.Merchant{ID: 1}, nil)
+	noFees(mockRepo)
+	noLimits(mockRepo)
+	mockRepo.On("LockClientAccount", uint(1), "MXN").Return(nil)
+	// Saldo de $1,000 con $700 ya retenidos: solo quedan $300 disponibles
+	mockRepo.On("GetClientBalance", uint(1), "MXN").Return(domain.MustParseMoney("1000"), nil)
+	mockRepo.On("GetClientHolds", uint(1), "MXN").Return(domain.MustParseMoney("700"), nil)
+	mockRepo.On("CreateTransaction", mock.Anything).Return(nil)
+
+	tx, err := service.AuthorizePayment(domain.MustParseMoney("300"), "MXN", 1, 1, "NETFLIX-1", "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "AUTHORIZED", tx.Status)
+	assert.Equal(t, now.Add(15*time.Minute), *tx.ExpiresAt)
+	// Retener no genera póliza
+	mockRepo.AssertNotCalled(t, "PostJournalEntry", mock.Anything)
+
+	_, err = service.AuthorizePayment(domain.MustParseMoney("300.01"), "MXN", 1, 1, "NETFLIX-2", "")
+	assert.ErrorIs(t, err, domain.ErrInsufficientFunds)
+}
+
+func TestAuthorizePayment_OpenCircuitHoldsNothing(t *testing.T) {
+	mockRepo := new(MockRepo)
+	service := newTestAuthorizationService(mockRepo, time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC))
+	service.payments.circuits = stubCircuits{err: fmt.Errorf("%w: Netflix falló 5 veces seguidas", domain.ErrMerchantUnavailable)}
+	mockRepo.On("GetMerchantByID", uint(1)).Return(&domain.Merchant{ID: 1}, nil)
+
+	tx, err := service.AuthorizePayment(domain.MustParseMoney("300"), "MXN", 1, 1, "NETFLIX-1", "")
+
+	assert.Nil(t, tx)
+	assert.ErrorIs(t, err, domain.ErrMerchantUnavailable)
+	mockRepo.AssertNotCalled(t, "CreateTransaction", mock.Anything)
+}
+
+func TestCapturePayment_PostsLedger(t *testing.T) {
+	mockRepo := new(MockRepo)
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	service := newTestAuthorizationService(mockRepo, now)
+
+	expiresAt := now.Add(time.Minute)
+	hold := &domain.Transaction{ID: uuid.New(), Amount: domain.MustParseMoney("300"), ClientID: 1, MerchantID: 1, Status: "AUTHORIZED", ExpiresAt: &expiresAt}
+	mockRepo.On("GetTransactionForUpdate", hold.ID).Return(hold, nil)
+	mockRepo.On("GetMerchantByID", uint(1)).Return(&domain.Merchant{ID: 1}, nil)
+	mockRepo.On("SaveTransaction", hold).Return(nil)
+	mockRepo.On("PostJournalEntry", mock.MatchedBy(func(e *domain.JournalEntry) bool {
+		return e.OperationType == domain.OperationPayment && e.OperationID == hold.ID
+	})).Return(nil)
+	paymentJobs(mockRepo)
+
+	tx, err := service.CapturePayment(hold.ID, 1)
+
+	// Capturar es cobrar: el biller se entera igual que en un pago directo
+	assert.NoError(t, err)
+	assert.Equal(t, "COMPLETED", tx.Status)
+	assert.Equal(t, "AUT-1", tx.AuthorizationNumber)
+	assert.Nil(t, tx.ExpiresAt)
+	service.payments.connector.(*MockConnector).AssertCalled(t, "NotifyPayment", mock.Anything, hold)
+	mockRepo.AssertCalled(t, "DeletePaymentJob", uint(3))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCapturePayment_BillerSilentStaysPending(t *testing.T) {
+	mockRepo := new(MockRepo)
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	service := newTestAuthorizationService(mockRepo, now)
+	connector := new(MockConnector)
+	connector.On("NotifyPayment", mock.Anything, mock.Anything).Return(nil, errors.New("Netflix no contestó: timeout"))
+	service.payments.connector = connector
+
+	expiresAt := now.Add(time.Minute)
+	hold := &domain.Transaction{ID: uuid.New(), Amount: domain.MustParseMoney("300"), ClientID: 1, MerchantID: 1, Status: "AUTHORIZED", ExpiresAt: &expiresAt}
+	mockRepo.On("GetTransactionForUpdate", hold.ID).Return(hold, nil)
+	mockRepo.On("GetMerchantByID", uint(1)).Return(&domain.Merchant{ID: 1}, nil)
+	mockRepo.On("SaveTransaction", hold).Return(nil)
+	mockRepo.On("PostJournalEntry", mock.Anything).Return(nil)
+	paymentJobs(mockRepo)
+
+	tx, err := service.CapturePayment(hold.ID, 1)
+	job := enqueuedJob(t, mockRepo)
+
+	// El biller pudo haberlo aplicado: el cargo se queda y la cola le vuelve a preguntar
+	assert.NoError(t, err)
+	assert.Equal(t, "PENDING", tx.Status)
+	assert.Equal(t, hold.ID, job.TransactionID)
+	assert.Nil(t, job.LockedUntil)
+	assert.Equal(t, now.Add(paymentRetryDelay), job.RunAt)
+	mockRepo.AssertNotCalled(t, "DeletePaymentJob", mock.Anything)
+	mockRepo.AssertNumberOfCalls(t, "PostJournalEntry", 1)
+}
+
+func TestCapturePayment_OpenCircuitKeepsHold(t *testing.T) {
+	mockRepo := new(MockRepo)
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	service := newTestAuthorizationService(mockRepo, now)
+	service.payments.circuits = stubCircuits{err: fmt.Errorf("%w: Netflix falló 5 veces seguidas", domain.ErrMerchantUnavailable)}
+
+	expiresAt := now.Add(time.Minute)
+	hold := &domain.Transaction{ID: uuid.New(), Amount: domain.MustParseMoney("300"), ClientID: 1, MerchantID: 1, Status: "AUTHORIZED", ExpiresAt: &expiresAt}
+	mockRepo.On("GetTransactionForUpdate", hold.ID).Return(hold, nil)
+	mockRepo.On("GetMerchantByID", uint(1)).Return(&domain.Merchant{ID: 1}, nil)
+
+	_, err := service.CapturePayment(hold.ID, 1)
+
+	assert.ErrorIs(t, err, domain.ErrMerchantUnavailable)
+	assert.Equal(t, "AUTHORIZED", hold.Status)
+	mockRepo.AssertNotCalled(t, "PostJournalEntry", mock.Anything)
+	mockRepo.AssertNotCalled(t, "EnqueuePaymentJob", mock.Anything)
+}
+
+func TestCapturePayment_ExpiredHold(t *testing.T) {
+	mockRepo := new(MockRepo)
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	service := newTestAuthorizationService(mockRepo, now)
+
+	expiresAt := now.Add(-time.Second)
+	hold := &domain.Transaction{ID: uuid.New(), Amount: domain.MustParseMoney("300"), ClientID: 1, Status: "AUTHORIZED", ExpiresAt: &expiresAt}
+	mockRepo.On("GetTransactionForUpdate", hold.ID).Return(hold, nil)
+
+	_, err := service.CapturePayment(hold.ID, 1)
+
+	assert.Equal(t, "la retención ya expiró", err.Error())
+	mockRepo.AssertNotCalled(t, "PostJournalEntry", mock.Anything)
+}
+
+func TestReleasePayment(t *testing.T) {
+	mockRepo := new(MockRepo)
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	service := newTestAuthorizationService(mockRepo, now)
+
+	expiresAt := now.Add(time.Minute)
+	hold := &domain.Transaction{ID: uuid.New(), Amount: domain.MustParseMoney("300"), ClientID: 1, Status: "AUTHORIZED", ExpiresAt: &expiresAt}
+	mockRepo.On("GetTransactionForUpdate", hold.ID).Return(hold, nil)
+	mockRepo.On("SaveTransaction", hold).Return(nil)
+
+	tx, err := service.ReleasePayment(hold.ID, 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "RELEASED", tx.Status)
+	mockRepo.AssertNotCalled(t, "PostJournalEntry", mock.Anything)
+
+	// Una retención ya liberada no se puede capturar
+	_, err = service.CapturePayment(hold.ID, 1)
+	assert.Equal(t, "la transacción no tiene una retención activa", err.Error())
+}
+
+func TestExpireHolds(t *testing.T) {
+	mockRepo := new(MockRepo)
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	service := newTestAuthorizationService(mockRepo, now)
+
+	mockRepo.On("ReleaseExpiredHolds", now).Return(int64(3), nil)
+
+	released, err := service.ExpireHolds()
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), released)
+}
@@ -0,0 +1,20 @@
+package services
+
+import (
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/scorazag/gopayhub/internal/core/ports"
+)
+
+// availableBalance es el saldo del libro mayor en esa moneda menos las retenciones (AUTHORIZED) vigentes.
+// Llamarla dentro de Atomic después de LockClientAccount para que no cambie bajo nuestros pies.
+func availableBalance(repo ports.PaymentRepository, clientID uint, currency string) (domain.Money, error) {
+	balance, err := repo.GetClientBalance(clientID, currency)
+	if err != nil {
+		return 0, err
+	}
+	holds, err := repo.GetClientHolds(clientID, currency)
+	if err != nil {
+		return 0, err
+	}
+	return balance - holds, nil
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/scorazag/gopayhub/internal/core/ports"
+)
+
+type billService struct {
+	repo       ports.PaymentRepository
+	connector  ports.MerchantConnector
+	cache      ports.BillCache
+	inquiryTTL time.Duration // Cuánto tiempo sirve una consulta para cobrar
+	now        func() time.Time
+	references *ReferenceValidator
+}
+
+func NewBillService(repo ports.PaymentRepository, connector ports.MerchantConnector, cache ports.BillCache, inquiryTTL time.Duration, references *ReferenceValidator) ports.BillService {
+	return &billService{repo: repo, connector: connector, cache: cache, inquiryTTL: inquiryTTL, now: time.Now, references: references}
+}
+
+// InquireBill le pregunta al biller cuánto se debe de la referencia. Si se escanea el mismo recibo
+// otra vez mientras la consulta sigue vigente y sin usar, se regresa la misma sin volver a preguntar.
+func (s *billService) InquireBill(merchantID uint, clientID uint, reference string) (*domain.Bill, error) {
+	reference = strings.TrimSpace(reference)
+	if reference == "" {
+		return nil, domain.ErrReferenceRequired
+	}
+
+	merchant, err := s.repo.GetMerchantByID(merchantID)
+	if err != nil {
+		return nil, notFoundAs(err, domain.ErrMerchantNotFound)
+	}
+	// Todavía no sabemos cuánto se va a pagar: solo formato, dígito verificador y vencimiento
+	if err := s.references.Validate(merchant, reference, 0); err != nil {
+		return nil, err
+	}
+	if bill, ok := s.cache.Find(clientID, merchant.ID, reference); ok {
+		return bill, nil
+	}
+
+	bill, err := s.connector.InquireBill(merchant, reference)
+	if errors.Is(err, domain.ErrBillNotFound) || errors.Is(err, domain.ErrMerchantUnavailable) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrMerchantUnavailable, err)
+	}
+
+	bill.InquiryID = uuid.New()
+	bill.MerchantID = merchant.ID
+	bill.ClientID = clientID
+	bill.Reference = reference
+	if bill.Currency == "" {
+		bill.Currency = merchant.Currency
+	}
+	if bill.Currency == "" {
+		bill.Currency = domain.BaseCurrency
+	}
+	bill.ExpiresAt = s.now().Add(s.inquiryTTL)
+	s.cache.Put(bill)
+	return bill, nil
+}
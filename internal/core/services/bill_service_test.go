@@ -0,0 +1,131 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// memBills es un ports.BillCache en memoria que no vence
+type memBills map[uuid.UUID]*domain.Bill
+
+func (m memBills) Take(id uuid.UUID, clientID uint) (*domain.Bill, bool) {
+	b, ok := m[id]
+	if !ok || b.ClientID != clientID {
+		return nil, false
+	}
+	delete(m, id)
+	return b, true
+}
+
+func (m memBills) Find(clientID uint, merchantID uint, reference string) (*domain.Bill, bool) {
+	for _, b := range m {
+		if b.ClientID == clientID && b.MerchantID == merchantID && b.Reference == reference {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+func (m memBills) Put(bill *domain.Bill) { m[bill.InquiryID] = bill }
+
+func TestInquireBill_AsksBillerOnceWhileCached(t *testing.T) {
+	mockRepo := new(MockRepo)
+	connector := new(MockConnector)
+	bills := memBills{}
+	service := NewBillService(mockRepo, connector, bills, 5*time.Minute, nil)
+
+	merchant := &domain.Merchant{ID: 1, Name: "CFE"}
+	mockRepo.On("GetMerchantByID", uint(1)).Return(merchant, nil)
+	connector.On("InquireBill", merchant, "CFE-123").Return(&domain.Bill{CustomerName: "María González", AmountDue: domain.MustParseMoney("845.50")}, nil).Once()
+
+	first, err := service.InquireBill(1, 7, " CFE-123 ")
+	assert.NoError(t, err)
+	assert.NotEqual(t, uuid.Nil, first.InquiryID)
+	assert.Equal(t, uint(1), first.MerchantID)
+	assert.Equal(t, uint(7), first.ClientID)
+	assert.Equal(t, "CFE-123", first.Reference)
+	assert.Equal(t, "MXN", first.Currency)
+	assert.WithinDuration(t, time.Now().Add(5*time.Minute), first.ExpiresAt, time.Minute)
+
+	// Escanear otra vez el mismo recibo regresa la misma consulta
+	second, err := service.InquireBill(1, 7, "CFE-123")
+	assert.NoError(t, err)
+	assert.Equal(t, first.InquiryID, second.InquiryID)
+	connector.AssertExpectations(t)
+
+	// Otro cliente no recibe la consulta del primero: tiene la suya
+	connector.On("InquireBill", merchant, "CFE-123").Return(&domain.Bill{AmountDue: domain.MustParseMoney("845.50")}, nil).Once()
+	other, err := service.InquireBill(1, 8, "CFE-123")
+	assert.NoError(t, err)
+	assert.NotEqual(t, first.InquiryID, other.InquiryID)
+}
+
+func TestInquireBill_Errors(t *testing.T) {
+	mockRepo := new(MockRepo)
+	connector := new(MockConnector)
+	service := NewBillService(mockRepo, connector, memBills{}, time.Minute, nil)
+	mockRepo.On("GetMerchantByID", uint(1)).Return(&domain.Merchant{ID: 1}, nil)
+	mockRepo.On("GetMerchantByID", uint(9)).Return(nil, domain.ErrNotFound)
+	connector.On("InquireBill", mock.Anything, "PAGADO").Return(nil, domain.ErrBillNotFound)
+	connector.On("InquireBill", mock.Anything, "CAIDO").Return(nil, errors.New("CFE contestó HTTP 502"))
+	connector.On("InquireBill", mock.Anything, "CIRCUITO").Return(nil, fmt.Errorf("%w: CFE: circuito abierto", domain.ErrMerchantUnavailable))
+
+	_, err := service.InquireBill(1, 7, "")
+	assert.ErrorIs(t, err, domain.ErrReferenceRequired)
+	_, err = service.InquireBill(9, 7, "CFE-1")
+	assert.ErrorIs(t, err, domain.ErrMerchantNotFound)
+	_, err = service.InquireBill(1, 7, "PAGADO")
+	assert.ErrorIs(t, err, domain.ErrBillNotFound)
+	_, err = service.InquireBill(1, 7, "CAIDO")
+	assert.ErrorIs(t, err, domain.ErrMerchantUnavailable)
+	// Con el circuito abierto el error ya trae su código y no se vuelve a envolver
+	_, err = service.InquireBill(1, 7, "CIRCUITO")
+	assert.ErrorIs(t, err, domain.ErrMerchantUnavailable)
+	assert.Equal(t, "el biller no respondió: CFE: circuito abierto", err.Error())
+}
+
+func TestProcessPayment_WithBillInquiry(t *testing.T) {
+	bill := &domain.Bill{InquiryID: uuid.New(), MerchantID: 1, ClientID: 1, Reference: "CFE-123", AmountDue: domain.MustParseMoney("845.50"), Currency: "MXN"}
+	bills := memBills{bill.InquiryID: bill}
+
+	mockRepo := new(MockRepo)
+	service := NewPaymentService(mockRepo, nil, NewLimitChecker(time.UTC), approvingConnector(), bills, nil, nil, nil)
+	pendingPayment(mockRepo)
+
+	// Sin monto ni referencia se paga lo que dice el recibo
+	tx, err := service.ProcessPayment(0, "", 1, 1, "", bill.InquiryID, "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.MustParseMoney("845.50"), tx.Amount)
+	assert.Equal(t, "CFE-123", tx.Reference)
+	assert.Equal(t, bill.InquiryID, *tx.BillInquiryID)
+	// La consulta ya quedó usada
+	_, err = service.ProcessPayment(0, "", 1, 1, "", bill.InquiryID, "")
+	assert.ErrorIs(t, err, domain.ErrBillInquiryExpired)
+}
+
+func TestProcessPayment_BillInquiryMustMatch(t *testing.T) {
+	bill := &domain.Bill{InquiryID: uuid.New(), MerchantID: 1, ClientID: 1, Reference: "CFE-123", AmountDue: domain.MustParseMoney("845.50"), Currency: "MXN"}
+	mockRepo := new(MockRepo)
+	service := NewPaymentService(mockRepo, nil, NewLimitChecker(time.UTC), approvingConnector(), memBills{bill.InquiryID: bill}, nil, nil, nil)
+
+	_, err := service.ProcessPayment(0, "", 1, 1, "", uuid.New(), "")
+	assert.ErrorIs(t, err, domain.ErrBillInquiryExpired)
+	_, err = service.ProcessPayment(0, "", 2, 1, "", bill.InquiryID, "")
+	assert.ErrorIs(t, err, domain.ErrBillInquiryMismatch)
+	_, err = service.ProcessPayment(0, "", 1, 1, "CFE-999", bill.InquiryID, "")
+	assert.ErrorIs(t, err, domain.ErrBillInquiryMismatch)
+	_, err = service.ProcessPayment(0, "USD", 1, 1, "", bill.InquiryID, "")
+	assert.ErrorIs(t, err, domain.ErrBillInquiryMismatch)
+	// La consulta de otro cliente no existe para este
+	_, err = service.ProcessPayment(0, "", 1, 2, "", bill.InquiryID, "")
+	assert.ErrorIs(t, err, domain.ErrBillInquiryExpired)
+	mockRepo.AssertNotCalled(t, "CreateTransaction", mock.Anything)
+}
@@ -1,43 +1,76 @@
 package services
 
 import (
-	"errors"
-
 	"github.com/scorazag/gopayhub/internal/core/domain"
 	"github.com/scorazag/gopayhub/internal/core/ports"
 )
 
 type CashOutService struct {
-	repo ports.PaymentRepository
+	repo   ports.PaymentRepository
+	limits *LimitChecker
 }
 
-func NewCashOutService(repo ports.PaymentRepository) ports.CashOutService {
-	return &CashOutService{repo: repo}
+func NewCashOutService(repo ports.PaymentRepository, limits *LimitChecker) ports.CashOutService {
+	return &CashOutService{repo: repo, limits: limits}
 }
 
-func (s *CashOutService) ProcessCashOut(amount float64, merchantID uint, clientID uint, reference string, idemKey string) (*domain.CashOut, error) {
+func (s *CashOutService) ProcessCashOut(amount domain.Money, currency string, merchantID uint, clientID uint, reference string, storeName string, idemKey string) (*domain.CashOut, error) {
 	// 1. Validar que el monto sea positivo
 	if amount <= 0 {
-		return nil, errors.New("el monto debe ser mayor a cero")
+		return nil, domain.ErrInvalidAmount
 	}
-	// 2. OBTENER EL SALDO ACTUAL usando el repo
-	balance, err := s.repo.GetClientBalance(clientID)
+	currency, err := domain.NormalizeCurrency(currency)
 	if err != nil {
 		return nil, err
 	}
-	// 3. VALIDACIÓN CLAVE: ¿Tiene dinero suficiente?
-	if amount > balance {
-		return nil, errors.New("insufficient funds")
+	// 2. Comisiones: se cobran del saldo además del monto retirado
+	fees, err := calculateFees(s.repo, domain.OperationCashOut, currency, clientID, nil, amount)
+	if err != nil {
+		return nil, err
 	}
-	// 4. Crear el objeto CashOut
+
+	// 3. Crear el objeto CashOut
 	cashout := &domain.CashOut{
-		Amount:    amount,
-		ClientID:  clientID,
-		Reference: reference,
-		Status:    "COMPLETED",
+		Amount:         amount,
+		Currency:       currency,
+		FeeAmount:      domain.TotalFees(fees),
+		Fees:           fees,
+		ClientID:       clientID,
+		Reference:      reference,
+		StoreName:      storeName,
+		Status:         "COMPLETED",
+		IdempotencyKey: idemKey,
 	}
-	// 5. Guardar en el repo
-	err = s.repo.CreateCashOut(cashout)
+
+	// 4. Validar saldo y cargar el retiro en la misma transacción.
+	// El bloqueo de la cuenta evita que dos retiros concurrentes pasen la validación con el mismo saldo.
+	err = s.repo.Atomic(func(repo ports.PaymentRepository) error {
+		if err := repo.LockClientAccount(clientID, currency); err != nil {
+			return err
+		}
+		// Las retenciones de pagos autorizados no se pueden retirar
+		available, err := availableBalance(repo, clientID, currency)
+		if err != nil {
+			return err
+		}
+		// VALIDACIÓN CLAVE: ¿Tiene dinero suficiente?
+		if amount+cashout.FeeAmount > available {
+			return domain.ErrInsufficientFunds
+		}
+		if err := s.limits.Check(repo, domain.LimitedOperation{
+			OperationType: domain.OperationCashOut,
+			Currency:      currency,
+			Amount:        amount,
+			ClientID:      clientID,
+			StoreName:     storeName,
+		}); err != nil {
+			return err
+		}
+		if err := repo.CreateCashOut(cashout); err != nil {
+			return err
+		}
+		return repo.PostJournalEntry(domain.NewCashOutEntry(cashout))
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -1,52 +1,95 @@
 package services
 
 import (
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/scorazag/gopayhub/internal/core/domain"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
 func TestProcessCashOut_Success(t *testing.T) {
 	mockRepo := new(MockRepo)
-	service := NewCashOutService(mockRepo)
+	service := NewCashOutService(mockRepo, NewLimitChecker(time.UTC))
 
 	// Mockeamos: El cliente tiene $1000 y el guardado es exitoso
-	mockRepo.On("GetClientBalance", uint(1)).Return(1000.0, nil)
+	noFees(mockRepo)
+	noLimits(mockRepo)
+	mockRepo.On("LockClientAccount", uint(1), "MXN").Return(nil)
+	mockRepo.On("GetClientBalance", uint(1), "MXN").Return(domain.MustParseMoney("1000.00"), nil)
+	mockRepo.On("GetClientHolds", uint(1), "MXN").Return(domain.Money(0), nil)
 	mockRepo.On("CreateCashOut", mock.Anything).Return(nil)
+	mockRepo.On("PostJournalEntry", mock.Anything).Return(nil)
 
-	res, err := service.ProcessCashOut(200.0, 0, 1, "REF-CASH-01", "idem-999")
+	res, err := service.ProcessCashOut(domain.MustParseMoney("200.00"), "MXN", 0, 1, "REF-CASH-01", "", "idem-999")
 
 	assert.NoError(t, err)
 	assert.NotNil(t, res)
-	assert.Equal(t, 200.0, res.Amount)
+	assert.Equal(t, domain.MustParseMoney("200.00"), res.Amount)
 	assert.Equal(t, "COMPLETED", res.Status)
 	mockRepo.AssertExpectations(t)
 }
 
 func TestProcessCashOut_InsufficientFunds(t *testing.T) {
 	mockRepo := new(MockRepo)
-	service := NewCashOutService(mockRepo)
+	service := NewCashOutService(mockRepo, NewLimitChecker(time.UTC))
 
 	// Mockeamos: El cliente solo tiene $50
-	mockRepo.On("GetClientBalance", uint(1)).Return(50.0, nil)
+	noFees(mockRepo)
+	noLimits(mockRepo)
+	mockRepo.On("LockClientAccount", uint(1), "MXN").Return(nil)
+	mockRepo.On("GetClientBalance", uint(1), "MXN").Return(domain.MustParseMoney("50.00"), nil)
+	mockRepo.On("GetClientHolds", uint(1), "MXN").Return(domain.Money(0), nil)
 
 	// Intenta sacar $100
-	res, err := service.ProcessCashOut(100.0, 0, 1, "REF-CASH-02", "")
+	res, err := service.ProcessCashOut(domain.MustParseMoney("100.00"), "MXN", 0, 1, "REF-CASH-02", "", "")
 
 	assert.Error(t, err)
 	assert.Nil(t, res)
-	assert.Equal(t, "insufficient funds", err.Error())
+	assert.ErrorIs(t, err, domain.ErrInsufficientFunds)
 	// Verificamos que no se intentó guardar nada
 	mockRepo.AssertNotCalled(t, "CreateCashOut", mock.Anything)
 }
 
 func TestProcessCashOut_AmountZero(t *testing.T) {
 	mockRepo := new(MockRepo)
-	service := NewCashOutService(mockRepo)
+	service := NewCashOutService(mockRepo, NewLimitChecker(time.UTC))
 
-	_, err := service.ProcessCashOut(-10.0, 0, 1, "REF-CASH-03", "")
+	_, err := service.ProcessCashOut(domain.MustParseMoney("-10.00"), "MXN", 0, 1, "REF-CASH-03", "", "")
 
 	assert.Error(t, err)
 	assert.Equal(t, "el monto debe ser mayor a cero", err.Error())
 }
+
+func TestProcessCashOut_ConcurrentNeverOverdraws(t *testing.T) {
+	repo := newMemRepo()
+	service := NewCashOutService(repo, NewLimitChecker(time.UTC))
+
+	// El cliente tiene $1,000 y le llegan 50 retiros simultáneos de $100
+	err := repo.PostJournalEntry(domain.NewDepositEntry(&domain.Deposit{Amount: domain.MoneyFromUnits(1000), ClientID: 1}))
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := service.ProcessCashOut(domain.MoneyFromUnits(100), "MXN", 0, 1, "REF-RACE", "", ""); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Solo caben 10 retiros y el saldo nunca queda negativo
+	balance, _ := repo.GetClientBalance(1, "MXN")
+	assert.Equal(t, 10, succeeded)
+	assert.Equal(t, domain.Money(0), balance)
+	assert.Equal(t, 10, len(repo.cashouts))
+}
@@ -1,39 +1,71 @@
 package services
 
 import (
-	"errors"
-
 	"github.com/scorazag/gopayhub/internal/core/domain"
 	"github.com/scorazag/gopayhub/internal/core/ports"
 )
 
 type depositService struct {
-	repo ports.PaymentRepository
+	repo   ports.PaymentRepository
+	limits *LimitChecker
 }
 
-func NewDepositService(repo ports.PaymentRepository) ports.DepositService {
-	return &depositService{repo: repo}
+func NewDepositService(repo ports.PaymentRepository, limits *LimitChecker) ports.DepositService {
+	return &depositService{repo: repo, limits: limits}
 }
 
-func (s *depositService) ProcessDeposit(amount float64, merchantID uint, clientID uint, reference string, idemKey string) (*domain.Deposit, error) {
+func (s *depositService) ProcessDeposit(amount domain.Money, currency string, merchantID uint, clientID uint, reference string, storeName string, idemKey string) (*domain.Deposit, error) {
 	// 1. Validaciones
-	if amount > 10000 {
-		return nil, errors.New("el monto excede el límite permitido para depósitos en efectivo")
-	}
 	if amount <= 0 {
-		return nil, errors.New("monto inválido")
+		return nil, domain.ErrInvalidAmount
+	}
+	currency, err := domain.NormalizeCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Comisiones: se descuentan del saldo que recibe el cliente
+	fees, err := calculateFees(s.repo, domain.OperationDeposit, currency, clientID, nil, amount)
+	if err != nil {
+		return nil, err
+	}
+	if domain.TotalFees(fees) >= amount {
+		return nil, domain.ErrFeeExceedsAmount
 	}
 
-	// 2. Crear objeto
+	// 3. Crear objeto
 	deposit := &domain.Deposit{
-		Amount:    amount,
-		ClientID:  clientID,
-		Reference: reference,
-		Status:    "COMPLETED",
+		Amount:         amount,
+		Currency:       currency,
+		FeeAmount:      domain.TotalFees(fees),
+		Fees:           fees,
+		ClientID:       clientID,
+		Reference:      reference,
+		StoreName:      storeName,
+		Status:         "COMPLETED",
+		IdempotencyKey: idemKey,
 	}
 
-	// 3. Guarda el deposito
-	err := s.repo.CreateDeposit(deposit)
+	// 4. Valida los límites y guarda el depósito y su póliza en la misma transacción.
+	// El bloqueo de la cuenta evita que dos depósitos concurrentes lean el mismo acumulado.
+	err = s.repo.Atomic(func(repo ports.PaymentRepository) error {
+		if err := repo.LockClientAccount(clientID, currency); err != nil {
+			return err
+		}
+		if err := s.limits.Check(repo, domain.LimitedOperation{
+			OperationType: domain.OperationDeposit,
+			Currency:      currency,
+			Amount:        amount,
+			ClientID:      clientID,
+			StoreName:     storeName,
+		}); err != nil {
+			return err
+		}
+		if err := repo.CreateDeposit(deposit); err != nil {
+			return err
+		}
+		return repo.PostJournalEntry(domain.NewDepositEntry(deposit))
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -2,7 +2,9 @@ package services
 
 import (
 	"testing"
+	"time"
 
+	"github.com/scorazag/gopayhub/internal/core/domain"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -10,14 +12,24 @@ import (
 func TestProcessDeposit_ExceedsLimit(t *testing.T) {
 	// Setup
 	mockRepo := new(MockRepo) // Usamos el mismo MockRepo que ya tiene CreateDeposit
-	service := NewDepositService(mockRepo)
+	service := NewDepositService(mockRepo, NewLimitChecker(time.UTC))
+
+	noFees(mockRepo)
+	mockRepo.On("LockClientAccount", uint(1), "MXN").Return(nil)
+	// El tope de 10k por depósito en efectivo ahora vive en la tabla de límites
+	mockRepo.On("FindLimits", mock.Anything).Return([]domain.Limit{
+		{Code: "CASH_DEPOSIT_MAX", OperationType: domain.OperationDeposit, Period: domain.LimitPerOperation, MaxAmount: domain.MoneyFromUnits(10000)},
+	}, nil)
 
 	// Ejecución: Intentamos depositar $11,000 (El límite es 10k)
-	res, err := service.ProcessDeposit(11000.0, 0, 1, "DEP-001", "")
+	res, err := service.ProcessDeposit(domain.MustParseMoney("11000.00"), "MXN", 0, 1, "DEP-001", "OXXO Centro", "")
 
 	// Aserciones
 	assert.Nil(t, res)
-	assert.Equal(t, "el monto excede el límite permitido para depósitos en efectivo", err.Error())
+	var limitErr *domain.LimitExceededError
+	assert.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, "CASH_DEPOSIT_MAX", limitErr.Limit.Code)
+	assert.Equal(t, "PER_OPERATION_LIMIT_EXCEEDED", limitErr.ErrorCode())
 
 	// Verificamos que NUNCA se llamó al repo para guardar
 	mockRepo.AssertNotCalled(t, "CreateDeposit", mock.Anything)
@@ -26,18 +38,22 @@ func TestProcessDeposit_ExceedsLimit(t *testing.T) {
 func TestProcessDeposit_Success(t *testing.T) {
 	// Setup
 	mockRepo := new(MockRepo)
-	service := NewDepositService(mockRepo)
+	service := NewDepositService(mockRepo, NewLimitChecker(time.UTC))
 
 	// Configuramos el mock para que acepte el guardado
+	noFees(mockRepo)
+	noLimits(mockRepo)
+	mockRepo.On("LockClientAccount", uint(1), "MXN").Return(nil)
 	mockRepo.On("CreateDeposit", mock.Anything).Return(nil)
+	mockRepo.On("PostJournalEntry", mock.Anything).Return(nil)
 
 	// Ejecución
-	res, err := service.ProcessDeposit(500.0, 0, 1, "DEP-OK", "idem-123")
+	res, err := service.ProcessDeposit(domain.MustParseMoney("500.00"), "MXN", 0, 1, "DEP-OK", "", "idem-123")
 
 	// Aserciones
 	assert.NoError(t, err)
 	assert.NotNil(t, res)
-	assert.Equal(t, 500.0, res.Amount)
+	assert.Equal(t, domain.MustParseMoney("500.00"), res.Amount)
 	assert.Equal(t, "COMPLETED", res.Status)
 
 	// Verificamos que se llamó al guardado exactamente una vez
@@ -0,0 +1,16 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/scorazag/gopayhub/internal/core/domain"
+)
+
+// notFoundAs cambia el domain.ErrNotFound del repositorio por el error del recurso que se buscaba.
+// Los demás errores (BD caída, timeout) pasan igual: no son un "no encontrado".
+func notFoundAs(err error, target *domain.Error) error {
+	if errors.Is(err, domain.ErrNotFound) {
+		return target
+	}
+	return err
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/scorazag/gopayhub/internal/core/ports"
+)
+
+// calculateFees busca el esquema de comisión más específico y regresa las líneas a cobrar, ya con IVA.
+// merchant es nil para depósitos y retiros. Sin esquema configurado no hay comisión.
+func calculateFees(repo ports.PaymentRepository, operationType string, currency string, clientID uint, merchant *domain.Merchant, amount domain.Money) ([]domain.Fee, error) {
+	var merchantID uint
+	var serviceType string
+	if merchant != nil {
+		merchantID = merchant.ID
+		serviceType = merchant.ServiceType
+	}
+
+	schedules, err := repo.FindFeeSchedules(operationType, currency, clientID, merchantID, serviceType)
+	if err != nil {
+		return nil, err
+	}
+	if len(schedules) == 0 {
+		return nil, nil
+	}
+
+	// Gana el más específico; en empate, el más nuevo (ID mayor)
+	best := schedules[0]
+	for _, s := range schedules[1:] {
+		if s.Specificity() > best.Specificity() || (s.Specificity() == best.Specificity() && s.ID > best.ID) {
+			best = s
+		}
+	}
+
+	amountFee := best.Calculate(amount)
+	if amountFee <= 0 {
+		return nil, nil
+	}
+	fees := []domain.Fee{{
+		OperationType: operationType,
+		FeeScheduleID: best.ID,
+		Description:   "Comisión " + best.Kind,
+		Amount:        amountFee,
+	}}
+
+	// Las comisiones llevan IVA según la región del cliente
+	if err := applyTaxes(repo, clientID, fees); err != nil {
+		return nil, err
+	}
+	return fees, nil
+}
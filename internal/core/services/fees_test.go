@@ -0,0 +1,87 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// taxRegion configura la región fiscal del cliente y su tasa de IVA
+func taxRegion(m *MockRepo, clientID uint, region string, rateBps int64) {
+	m.On("GetClientByID", clientID).Return(&domain.Client{ID: clientID, Region: region}, nil)
+	m.On("GetTaxRate", region).Return(&domain.TaxRate{Region: region, Name: "IVA", RateBps: rateBps}, nil)
+}
+
+func TestCalculateFees_MostSpecificWins(t *testing.T) {
+	mockRepo := new(MockRepo)
+	clientID := uint(1)
+	merchantID := uint(2)
+	merchant := &domain.Merchant{ID: merchantID, ServiceType: "ELECTRICITY"}
+
+	mockRepo.On("FindFeeSchedules", domain.OperationPayment, "MXN", clientID, merchantID, "ELECTRICITY").Return([]domain.FeeSchedule{
+		{ID: 1, Kind: domain.FeeFlat, FlatAmount: domain.MustParseMoney("10")},                                              // General
+		{ID: 2, Kind: domain.FeeFlat, FlatAmount: domain.MustParseMoney("8"), ServiceType: "ELECTRICITY"},                   // Por tipo de servicio
+		{ID: 3, Kind: domain.FeePercentage, RateBps: 100, MerchantID: &merchantID},                                          // Por merchant
+		{ID: 4, Kind: domain.FeeFlat, FlatAmount: domain.MustParseMoney("3"), ClientID: &clientID, MerchantID: &merchantID}, // Cliente + merchant
+	}, nil)
+	taxRegion(mockRepo, clientID, domain.RegionGeneral, 1600)
+
+	fees, err := calculateFees(mockRepo, domain.OperationPayment, "MXN", clientID, merchant, domain.MustParseMoney("500"))
+
+	assert.NoError(t, err)
+	assert.Len(t, fees, 1)
+	assert.Equal(t, uint(4), fees[0].FeeScheduleID)
+	assert.Equal(t, domain.MustParseMoney("3.00"), fees[0].Amount)
+}
+
+func TestProcessCashOut_FeeCountsAgainstBalance(t *testing.T) {
+	mockRepo := new(MockRepo)
+	service := NewCashOutService(mockRepo, NewLimitChecker(time.UTC))
+
+	// Retirar $1,000 cuesta $15 de comisión (más IVA) y el cliente tiene exactamente $1,000
+	mockRepo.On("FindFeeSchedules", domain.OperationCashOut, "MXN", uint(1), uint(0), "").Return([]domain.FeeSchedule{
+		{ID: 1, Kind: domain.FeeFlat, FlatAmount: domain.MustParseMoney("15")},
+	}, nil)
+	taxRegion(mockRepo, 1, domain.RegionGeneral, 1600)
+	mockRepo.On("LockClientAccount", uint(1), "MXN").Return(nil)
+	mockRepo.On("GetClientBalance", uint(1), "MXN").Return(domain.MustParseMoney("1000"), nil)
+	mockRepo.On("GetClientHolds", uint(1), "MXN").Return(domain.Money(0), nil)
+
+	_, err := service.ProcessCashOut(domain.MustParseMoney("1000"), "MXN", 0, 1, "REF-FEE", "", "")
+
+	assert.ErrorIs(t, err, domain.ErrInsufficientFunds)
+	mockRepo.AssertNotCalled(t, "CreateCashOut", mock.Anything)
+}
+
+func TestProcessPayment_ReturnsFeeLines(t *testing.T) {
+	mockRepo := new(MockRepo)
+	service := NewPaymentService(mockRepo, nil, NewLimitChecker(time.UTC), approvingConnector(), nil, nil, nil, nil)
+
+	mockRepo.On("GetMerchantByID", uint(1)).Return(&domain.Merchant{ID: 1, ServiceType: "STREAMING"}, nil)
+	mockRepo.On("FindFeeSchedules", domain.OperationPayment, "MXN", uint(1), uint(1), "STREAMING").Return([]domain.FeeSchedule{
+		{ID: 9, Kind: domain.FeePercentage, RateBps: 200, MinFee: domain.MustParseMoney("4")},
+	}, nil)
+	noLimits(mockRepo)
+	taxRegion(mockRepo, 1, domain.RegionGeneral, 1600)
+	mockRepo.On("LockClientAccount", uint(1), "MXN").Return(nil)
+	funded(mockRepo, "MXN")
+	mockRepo.On("CreateTransaction", mock.Anything).Return(nil)
+	paymentJobs(mockRepo)
+	mockRepo.On("SaveTransaction", mock.Anything).Return(nil)
+	mockRepo.On("PostJournalEntry", mock.MatchedBy(func(e *domain.JournalEntry) bool {
+		// Pago + comisión + IVA de la comisión
+		return e.Validate() == nil && len(e.Postings) == 6
+	})).Return(nil)
+
+	tx, err := service.ProcessPayment(domain.MustParseMoney("149.00"), "MXN", 1, 1, "NFX-1", uuid.Nil, "")
+
+	assert.NoError(t, err)
+	// $4.00 de comisión + $0.64 de IVA
+	assert.Equal(t, domain.MustParseMoney("4.64"), tx.FeeAmount)
+	assert.Len(t, tx.Fees, 1)
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,34 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/scorazag/gopayhub/internal/core/ports"
+)
+
+// convertForMerchant cotiza el tipo de cambio cuando el merchant cobra en otra moneda que la del cliente.
+// Si cobran en la misma moneda no hay conversión y regresa un FXConversion vacío.
+func convertForMerchant(fx ports.FXRateProvider, amount domain.Money, currency string, merchant *domain.Merchant) (domain.FXConversion, error) {
+	merchantCurrency := merchant.Currency
+	if merchantCurrency == "" {
+		merchantCurrency = domain.BaseCurrency
+	}
+	if merchantCurrency == currency {
+		return domain.FXConversion{}, nil
+	}
+
+	quote, err := fx.GetRate(currency, merchantCurrency)
+	if err != nil {
+		return domain.FXConversion{}, fmt.Errorf("%w %s/%s: %w", domain.ErrFXRateUnavailable, currency, merchantCurrency, err)
+	}
+	return domain.NewFXConversion(amount, quote)
+}
+
+// merchantAmount es lo que recibe el merchant en su moneda: el monto convertido si hubo tipo de cambio
+func merchantAmount(amount domain.Money, conversion domain.FXConversion) domain.Money {
+	if conversion.SettlementCurrency == "" {
+		return amount
+	}
+	return conversion.SettlementAmount
+}
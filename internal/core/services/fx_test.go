@@ -0,0 +1,115 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockFX struct {
+	mock.Mock
+}
+
+func (m *MockFX) GetRate(from, to string) (*domain.FXQuote, error) {
+	args := m.Called(from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.FXQuote), args.Error(1)
+}
+
+func TestProcessPayment_ConvertsToMerchantCurrency(t *testing.T) {
+	mockRepo := new(MockRepo)
+	mockFX := new(MockFX)
+	service := NewPaymentService(mockRepo, mockFX, NewLimitChecker(time.UTC), approvingConnector(), nil, nil, nil, nil)
+
+	asOf := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	mockRepo.On("GetMerchantByID", uint(1)).Return(&domain.Merchant{ID: 1, Currency: "MXN"}, nil)
+	noFees(mockRepo)
+	noLimits(mockRepo)
+	mockFX.On("GetRate", "USD", "MXN").Return(&domain.FXQuote{From: "USD", To: "MXN", Rate: "17.05", Source: "stub", AsOf: asOf}, nil)
+	mockRepo.On("LockClientAccount", uint(1), "USD").Return(nil)
+	funded(mockRepo, "USD")
+	mockRepo.On("CreateTransaction", mock.Anything).Return(nil)
+	paymentJobs(mockRepo)
+	mockRepo.On("SaveTransaction", mock.Anything).Return(nil)
+	mockRepo.On("PostJournalEntry", mock.MatchedBy(func(e *domain.JournalEntry) bool {
+		return e.Validate() == nil && len(e.Postings) == 4
+	})).Return(nil)
+
+	tx, err := service.ProcessPayment(domain.MustParseMoney("20"), "usd", 1, 1, "CFE-USD", uuid.Nil, "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "USD", tx.Currency)
+	assert.Equal(t, domain.MustParseMoney("341"), tx.SettlementAmount)
+	assert.Equal(t, "MXN", tx.SettlementCurrency)
+	assert.Equal(t, "17.05", tx.FXRate)
+	assert.Equal(t, "stub", tx.FXSource)
+	assert.Equal(t, asOf, *tx.FXRateAt)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestProcessPayment_ReferenceAmountIsInMerchantCurrency(t *testing.T) {
+	// El recibo de $341.00 MXN viene en la referencia; el cliente paga $20 USD a 17.05
+	references, err := NewReferenceValidator(domain.ReferenceRules{
+		ByServiceType: map[string]domain.ReferenceRule{"ELECTRICITY": {Name: "código de barras", MinLength: 8, Charset: domain.CharsetDigits, Amount: &domain.ReferenceField{Start: 0, Length: 8}}},
+	}, time.UTC)
+	assert.NoError(t, err)
+	mockRepo := new(MockRepo)
+	mockFX := new(MockFX)
+	service := NewPaymentService(mockRepo, mockFX, NewLimitChecker(time.UTC), approvingConnector(), nil, references, nil, nil)
+
+	mockRepo.On("GetMerchantByID", uint(1)).Return(&domain.Merchant{ID: 1, Currency: "MXN", ServiceType: "ELECTRICITY"}, nil)
+	noFees(mockRepo)
+	noLimits(mockRepo)
+	mockFX.On("GetRate", "USD", "MXN").Return(&domain.FXQuote{From: "USD", To: "MXN", Rate: "17.05", Source: "stub", AsOf: time.Now()}, nil)
+	mockRepo.On("LockClientAccount", uint(1), "USD").Return(nil)
+	funded(mockRepo, "USD")
+	mockRepo.On("CreateTransaction", mock.Anything).Return(nil)
+	paymentJobs(mockRepo)
+	mockRepo.On("SaveTransaction", mock.Anything).Return(nil)
+	mockRepo.On("PostJournalEntry", mock.Anything).Return(nil)
+
+	tx, err := service.ProcessPayment(domain.MustParseMoney("20"), "USD", 1, 1, "00034100", uuid.Nil, "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.MustParseMoney("341"), tx.SettlementAmount)
+
+	// El monto del cliente no se compara contra el del recibo
+	_, err = service.ProcessPayment(domain.MustParseMoney("341"), "USD", 1, 1, "00034100", uuid.Nil, "")
+	assert.ErrorIs(t, err, domain.ErrReferenceAmountMismatch)
+}
+
+func TestProcessPayment_SameCurrencySkipsFX(t *testing.T) {
+	mockRepo := new(MockRepo)
+	mockFX := new(MockFX)
+	service := NewPaymentService(mockRepo, mockFX, NewLimitChecker(time.UTC), approvingConnector(), nil, nil, nil, nil)
+
+	mockRepo.On("GetMerchantByID", uint(1)).Return(&domain.Merchant{ID: 1}, nil) // Sin moneda = MXN
+	noFees(mockRepo)
+	noLimits(mockRepo)
+	mockRepo.On("LockClientAccount", uint(1), "MXN").Return(nil)
+	funded(mockRepo, "MXN")
+	mockRepo.On("CreateTransaction", mock.Anything).Return(nil)
+	paymentJobs(mockRepo)
+	mockRepo.On("SaveTransaction", mock.Anything).Return(nil)
+	mockRepo.On("PostJournalEntry", mock.Anything).Return(nil)
+
+	tx, err := service.ProcessPayment(domain.MustParseMoney("20"), "", 1, 1, "CFE-MXN", uuid.Nil, "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "MXN", tx.Currency)
+	assert.Empty(t, tx.SettlementCurrency)
+	mockFX.AssertNotCalled(t, "GetRate", mock.Anything, mock.Anything)
+}
+
+func TestProcessPayment_InvalidCurrency(t *testing.T) {
+	service := NewPaymentService(new(MockRepo), new(MockFX), NewLimitChecker(time.UTC), approvingConnector(), nil, nil, nil, nil)
+
+	_, err := service.ProcessPayment(domain.MustParseMoney("20"), "XXX1", 1, 1, "REF", uuid.Nil, "")
+	assert.ErrorIs(t, err, domain.ErrInvalidCurrency)
+}
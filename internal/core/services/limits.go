@@ -0,0 +1,68 @@
+package services
+
+import (
+	"time"
+
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/scorazag/gopayhub/internal/core/ports"
+)
+
+// LimitChecker valida las operaciones contra los límites configurados en la base.
+// Los límites se leen en cada operación, así que se pueden editar sin reiniciar.
+type LimitChecker struct {
+	location *time.Location // Zona horaria del día hábil para los acumulados diarios y mensuales
+	now      func() time.Time
+}
+
+func NewLimitChecker(location *time.Location) *LimitChecker {
+	if location == nil {
+		location = time.UTC
+	}
+	return &LimitChecker{location: location, now: time.Now}
+}
+
+// Check regresa *domain.LimitExceededError con el primer límite que la operación rebasaría.
+// Debe correr dentro de Atomic y después de LockClientAccount para que dos operaciones
+// del mismo cliente no lean el mismo acumulado; los límites compartidos se bloquean aquí
+// (en orden de ID, como los regresa FindLimits) porque los usan clientes distintos.
+func (c *LimitChecker) Check(repo ports.PaymentRepository, op domain.LimitedOperation) error {
+	limits, err := repo.FindLimits(op)
+	if err != nil {
+		return err
+	}
+
+	now := c.now().In(c.location)
+	for _, limit := range limits {
+		if limit.Period == domain.LimitPerOperation {
+			if op.Amount > limit.MaxAmount {
+				return &domain.LimitExceededError{Limit: limit, Attempted: op.Amount}
+			}
+			continue
+		}
+
+		if limit.Shared {
+			if err := repo.LockLimit(limit.ID); err != nil {
+				return err
+			}
+		}
+		used, err := repo.SumOperations(limit.UsageQuery(op, limit.PeriodStart(now)))
+		if err != nil {
+			return err
+		}
+		if used+op.Amount > limit.MaxAmount {
+			return &domain.LimitExceededError{Limit: limit, Used: used, Attempted: op.Amount}
+		}
+	}
+	return nil
+}
+
+// paymentLimitOperation describe un pago (o retención) para validarlo contra los límites
+func paymentLimitOperation(tx *domain.Transaction) domain.LimitedOperation {
+	return domain.LimitedOperation{
+		OperationType: domain.OperationPayment,
+		Currency:      tx.Currency,
+		Amount:        tx.Amount,
+		ClientID:      tx.ClientID,
+		MerchantID:    tx.MerchantID,
+	}
+}
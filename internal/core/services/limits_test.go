@@ -0,0 +1,87 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func fixedLimitChecker(t *testing.T, now time.Time) *LimitChecker {
+	loc, err := time.LoadLocation("America/Mexico_City")
+	assert.NoError(t, err)
+	c := NewLimitChecker(loc)
+	c.now = func() time.Time { return now }
+	return c
+}
+
+func TestLimitChecker_DailyUsesBusinessDay(t *testing.T) {
+	mockRepo := new(MockRepo)
+	// 03:00 UTC del 17 son las 21:00 del 16 en CDMX: el día hábil empezó el 16 a medianoche local
+	checker := fixedLimitChecker(t, time.Date(2026, 10, 17, 3, 0, 0, 0, time.UTC))
+	op := domain.LimitedOperation{OperationType: domain.OperationCashOut, Currency: "MXN", Amount: domain.MustParseMoney("1500"), ClientID: 7, StoreName: "OXXO Centro"}
+
+	daily := domain.Limit{Code: "CASHOUT_DAILY", OperationType: domain.OperationCashOut, Period: domain.LimitDaily, MaxAmount: domain.MoneyFromUnits(5000)}
+	mockRepo.On("FindLimits", op).Return([]domain.Limit{daily}, nil)
+
+	clientID := uint(7)
+	mockRepo.On("SumOperations", domain.LimitUsageQuery{
+		OperationType: domain.OperationCashOut,
+		Currency:      "MXN",
+		ClientID:      &clientID,
+		Since:         time.Date(2026, 10, 16, 0, 0, 0, 0, checker.location),
+	}).Return(domain.MustParseMoney("3500.01"), nil)
+
+	err := checker.Check(mockRepo, op)
+
+	var limitErr *domain.LimitExceededError
+	assert.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, "CASHOUT_DAILY", limitErr.Limit.Code)
+	assert.Equal(t, "DAILY_LIMIT_EXCEEDED", limitErr.ErrorCode())
+	assert.Equal(t, domain.MustParseMoney("3500.01"), limitErr.Used)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestLimitChecker_SharedStoreLimitAndExactCap(t *testing.T) {
+	mockRepo := new(MockRepo)
+	checker := fixedLimitChecker(t, time.Date(2026, 10, 16, 18, 0, 0, 0, time.UTC))
+	op := domain.LimitedOperation{OperationType: domain.OperationDeposit, Currency: "MXN", Amount: domain.MustParseMoney("1000"), ClientID: 7, StoreName: "OXXO Centro"}
+
+	// Tope mensual de efectivo de la tienda, sumando a todos los clientes
+	store := domain.Limit{ID: 4, Code: "STORE_MONTHLY", OperationType: domain.OperationDeposit, Period: domain.LimitMonthly, StoreName: "OXXO Centro", Shared: true, MaxAmount: domain.MoneyFromUnits(100000)}
+	perOp := domain.Limit{Code: "CASH_DEPOSIT_MAX", OperationType: domain.OperationDeposit, Period: domain.LimitPerOperation, MaxAmount: domain.MoneyFromUnits(10000)}
+	mockRepo.On("FindLimits", op).Return([]domain.Limit{perOp, store}, nil)
+	mockRepo.On("SumOperations", domain.LimitUsageQuery{
+		OperationType: domain.OperationDeposit,
+		Currency:      "MXN",
+		StoreName:     "OXXO Centro",
+		Since:         time.Date(2026, 10, 1, 0, 0, 0, 0, checker.location),
+	}).Return(domain.MoneyFromUnits(99000), nil)
+	// Otro cliente de la misma tienda espera a que terminemos antes de leer el acumulado
+	mockRepo.On("LockLimit", uint(4)).Return(nil)
+
+	// Llegar exactamente al tope está permitido
+	assert.NoError(t, checker.Check(mockRepo, op))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestLimitChecker_PerOperationSkipsUsage(t *testing.T) {
+	mockRepo := new(MockRepo)
+	checker := NewLimitChecker(time.UTC)
+	op := domain.LimitedOperation{OperationType: domain.OperationPayment, Currency: "MXN", Amount: domain.MustParseMoney("600"), ClientID: 1, MerchantID: 3}
+
+	merchantID := uint(3)
+	mockRepo.On("FindLimits", op).Return([]domain.Limit{
+		{Code: "NETFLIX_MAX", OperationType: domain.OperationPayment, Period: domain.LimitPerOperation, MerchantID: &merchantID, MaxAmount: domain.MoneyFromUnits(500)},
+	}, nil)
+
+	err := checker.Check(mockRepo, op)
+
+	var limitErr *domain.LimitExceededError
+	assert.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, "PER_OPERATION_LIMIT_EXCEEDED", limitErr.ErrorCode())
+	assert.Contains(t, err.Error(), "NETFLIX_MAX")
+	mockRepo.AssertNotCalled(t, "SumOperations", mock.Anything)
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/scorazag/gopayhub/internal/core/ports"
+)
+
+// memRepo es un repositorio en memoria que imita la semántica de Postgres que nos importa:
+// Atomic guarda todo o nada, y LockClientAccount bloquea la cuenta hasta que termina la transacción.
+// Lo usamos en pruebas de concurrencia donde un mock no sirve.
+type memRepo struct {
+	// Los métodos que no implementamos hacen panic si una prueba los llega a usar
+	ports.PaymentRepository
+
+	mu       sync.Mutex
+	locks    map[uint]*sync.Mutex
+	postings []domain.Posting
+	cashouts []domain.CashOut
+	deposits []domain.Deposit
+	txs      []domain.Transaction
+}
+
+func newMemRepo() *memRepo {
+	return &memRepo{locks: map[uint]*sync.Mutex{}}
+}
+
+// memTx es la vista de memRepo dentro de Atomic: acumula escrituras y las aplica al final
+type memTx struct {
+	*memRepo
+	held     []*sync.Mutex
+	postings []domain.Posting
+	cashouts []domain.CashOut
+	deposits []domain.Deposit
+	txs      []domain.Transaction
+}
+
+func (r *memRepo) Atomic(fn func(repo ports.PaymentRepository) error) error {
+	tx := &memTx{memRepo: r}
+	defer func() {
+		for _, l := range tx.held {
+			l.Unlock()
+		}
+	}()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.postings = append(r.postings, tx.postings...)
+	r.cashouts = append(r.cashouts, tx.cashouts...)
+	r.deposits = append(r.deposits, tx.deposits...)
+	r.txs = append(r.txs, tx.txs...)
+	return nil
+}
+
+func (r *memRepo) clientLock(clientID uint) *sync.Mutex {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	l, ok := r.locks[clientID]
+	if !ok {
+		l = &sync.Mutex{}
+		r.locks[clientID] = l
+	}
+	return l
+}
+
+func (r *memRepo) LockClientAccount(clientID uint, currency string) error {
+	return errors.New("LockClientAccount requiere una transacción")
+}
+
+// El fake bloquea por cliente sin importar la moneda; es más estricto que Postgres, no menos
+func (t *memTx) LockClientAccount(clientID uint, currency string) error {
+	l := t.clientLock(clientID)
+	l.Lock()
+	t.held = append(t.held, l)
+	return nil
+}
+
+func balanceOf(postings []domain.Posting, clientID uint, currency string) domain.Money {
+	var balance domain.Money
+	for _, p := range postings {
+		if p.AccountCode != domain.ClientAccountCode(clientID) || p.Currency != currency {
+			continue
+		}
+		if p.Direction == domain.Credit {
+			balance += p.Amount
+		} else {
+			balance -= p.Amount
+		}
+	}
+	return balance
+}
+
+func (r *memRepo) GetClientBalance(clientID uint, currency string) (domain.Money, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return balanceOf(r.postings, clientID, currency), nil
+}
+
+func (t *memTx) GetClientBalance(clientID uint, currency string) (domain.Money, error) {
+	committed, _ := t.memRepo.GetClientBalance(clientID, currency)
+	// Cedemos el procesador para que una carrera, si existe, se manifieste
+	runtime.Gosched()
+	return committed + balanceOf(t.postings, clientID, currency), nil
+}
+
+// El fake no maneja retenciones
+func (r *memRepo) GetClientHolds(clientID uint, currency string) (domain.Money, error) {
+	return 0, nil
+}
+
+// El fake no cobra comisiones
+func (r *memRepo) FindFeeSchedules(operationType string, currency string, clientID uint, merchantID uint, serviceType string) ([]domain.FeeSchedule, error) {
+	return nil, nil
+}
+
+// El fake no tiene límites
+func (r *memRepo) FindLimits(op domain.LimitedOperation) ([]domain.Limit, error) {
+	return nil, nil
+}
+
+func (r *memRepo) PostJournalEntry(entry *domain.JournalEntry) error {
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.postings = append(r.postings, entry.Postings...)
+	return nil
+}
+
+func (t *memTx) PostJournalEntry(entry *domain.JournalEntry) error {
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+	t.postings = append(t.postings, entry.Postings...)
+	return nil
+}
+
+func (r *memRepo) CreateCashOut(c *domain.CashOut) error {
+	c.ID = uuid.New()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cashouts = append(r.cashouts, *c)
+	return nil
+}
+
+func (t *memTx) CreateCashOut(c *domain.CashOut) error {
+	c.ID = uuid.New()
+	t.cashouts = append(t.cashouts, *c)
+	return nil
+}
+
+func (r *memRepo) CreateDeposit(d *domain.Deposit) error {
+	d.ID = uuid.New()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deposits = append(r.deposits, *d)
+	return nil
+}
+
+func (t *memTx) CreateDeposit(d *domain.Deposit) error {
+	d.ID = uuid.New()
+	t.deposits = append(t.deposits, *d)
+	return nil
+}
+
+func (r *memRepo) CreateTransaction(tx *domain.Transaction) error {
+	tx.ID = uuid.New()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.txs = append(r.txs, *tx)
+	return nil
+}
+
+func (t *memTx) CreateTransaction(tx *domain.Transaction) error {
+	tx.ID = uuid.New()
+	t.txs = append(t.txs, *tx)
+	return nil
+}
+
+func (r *memRepo) GetClientByApiKey(apiKey string) (*domain.Client, error) {
+	return nil, errors.New("no implementado")
+}
+
+func (r *memRepo) GetMerchantByID(id uint) (*domain.Merchant, error) {
+	return &domain.Merchant{ID: id}, nil
+}
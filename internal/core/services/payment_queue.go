@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/scorazag/gopayhub/internal/core/ports"
+)
+
+const (
+	// paymentJobLease es cuánto tiempo un worker tiene apartado un job de pago o de devolución: la llamada
+	// más larga al biller más margen para la BD. Así el apartado no vence mientras el biller contesta.
+	paymentJobLease = domain.MaxMerchantTimeout + time.Minute
+	// paymentReconcileAttempts es a partir de cuántos intentos sin respuesta se avisa en el log para conciliar
+	// con el biller. El pago nunca se da por no aplicado solo porque el biller no contesta: pudo haberlo aplicado.
+	paymentReconcileAttempts = 5
+	// paymentRetryDelay es la espera antes del primer reintento; se duplica en cada intento hasta paymentMaxRetryDelay
+	paymentRetryDelay    = 30 * time.Second
+	paymentMaxRetryDelay = time.Hour
+)
+
+// ProcessQueuedPayment toma el siguiente pago encolado y lo confirma con el biller.
+// Si el biller no contesta se reintenta más tarde, sin límite de intentos; el biller reconoce el reintento
+// por el ID de la transacción. Solo un rechazo explícito deja el pago FAILED.
+func (s *paymentService) ProcessQueuedPayment() (bool, error) {
+	job, err := s.repo.ClaimPaymentJob(s.now(), paymentJobLease)
+	if err != nil || job == nil {
+		return false, err
+	}
+
+	// Si algo falla antes de preguntarle al biller, el job se queda apartado y otro worker lo retoma al vencer
+	tx, err := s.repo.GetTransaction(job.TransactionID, job.ClientID)
+	if err != nil {
+		return true, err
+	}
+	if tx.Status != "PENDING" {
+		// Ya lo terminó otro worker al que se le venció el apartado
+		return true, s.repo.DeletePaymentJob(job.ID)
+	}
+	merchant, err := s.repo.GetMerchantByID(tx.MerchantID)
+	if err != nil {
+		return true, err
+	}
+
+	// Con el circuito abierto ni se intenta; la espera no cuenta como intento
+	if err := s.available(merchant); err != nil {
+		job.Attempts--
+		return true, s.retryLater(job, err)
+	}
+
+	confirmation, notifyErr := s.connector.NotifyPayment(merchant, tx)
+	if notifyErr != nil {
+		if job.Attempts >= paymentReconcileAttempts {
+			log.Printf("[pagos] ALERTA: la transacción %s lleva %d intentos sin respuesta del biller %d; revisar con el biller: %v", tx.ID, job.Attempts, merchant.ID, notifyErr)
+		}
+		return true, s.retryLater(job, notifyErr)
+	}
+
+	// Los rechazos del biller son el resultado del pago, no un error del worker
+	if err := s.settle(tx, confirmation, job); err != nil {
+		var coded domain.CodedError
+		if !errors.As(err, &coded) {
+			return true, err
+		}
+	}
+	s.notifyClient(tx)
+	return true, nil
+}
+
+// retryLater regresa el job a la cola; la espera crece con cada intento
+func (s *paymentService) retryLater(job *domain.PaymentJob, cause error) error {
+	job.RunAt = s.now().Add(retryDelay(job.Attempts))
+	job.LockedUntil = nil
+	job.LastError = truncate(cause.Error(), 300)
+	return s.repo.SavePaymentJob(job)
+}
+
+// retryDelay es la espera antes de volver a preguntarle al biller después de attempts intentos sin respuesta
+func retryDelay(attempts int) time.Duration {
+	if shift := max(attempts-1, 0); shift < 7 { // 30s << 7 ya rebasa la hora
+		return min(paymentRetryDelay<<shift, paymentMaxRetryDelay)
+	}
+	return paymentMaxRetryDelay
+}
+
+// notifyClient avisa por webhook cómo terminó el pago; si falla, el cliente todavía puede consultarlo
+func (s *paymentService) notifyClient(tx *domain.Transaction) {
+	if s.notifier == nil {
+		return
+	}
+	client, err := s.repo.GetClientByID(tx.ClientID)
+	if err != nil {
+		log.Printf("[pagos] no se pudo avisar al cliente %d de la transacción %s: %v", tx.ClientID, tx.ID, err)
+		return
+	}
+	if client.WebhookURL == "" {
+		return
+	}
+	if err := s.notifier.NotifyTransaction(client, tx); err != nil {
+		log.Printf("[pagos] no se pudo avisar al cliente %d de la transacción %s: %v", tx.ClientID, tx.ID, err)
+	}
+}
+
+// RunPaymentWorkers arranca workers que confirman los pagos asíncronos hasta que ctx termine.
+// Mientras haya pagos en la cola los toman uno tras otro; si está vacía esperan idle antes de volver a buscar.
+func RunPaymentWorkers(ctx context.Context, service ports.PaymentService, workers int, idle time.Duration) {
+	runWorkers(ctx, "pagos", service.ProcessQueuedPayment, workers, idle)
+}
+
+// runWorkers corre process en workers goroutines hasta que ctx termine; name es para el log
+func runWorkers(ctx context.Context, name string, process func() (bool, error), workers int, idle time.Duration) {
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				found, err := process()
+				if err != nil {
+					log.Printf("[%s] error en la cola de %s: %v", name, name, err)
+				}
+				if found && err == nil {
+					continue
+				}
+				select {
+				case <-ctx.Done():
+				case <-time.After(idle):
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
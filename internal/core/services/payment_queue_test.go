@@ -0,0 +1,150 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockNotifier struct {
+	mock.Mock
+}
+
+func (m *MockNotifier) NotifyTransaction(client *domain.Client, tx *domain.Transaction) error {
+	return m.Called(client, tx).Error(0)
+}
+
+func TestProcessPaymentAsync_EnqueuesWithoutCallingBiller(t *testing.T) {
+	mockRepo := new(MockRepo)
+	connector := new(MockConnector)
+	service := NewPaymentService(mockRepo, nil, NewLimitChecker(time.UTC), connector, nil, nil, nil, nil)
+	entries := pendingPayment(mockRepo)
+
+	tx, err := service.ProcessPaymentAsync(domain.MustParseMoney("150.00"), "MXN", 1, 1, "CFE-1", uuid.Nil, "llave-1")
+	job := enqueuedJob(t, mockRepo)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "PENDING", tx.Status)
+	// El cargo queda aplicado mientras el pago espera en la cola
+	assert.Len(t, *entries, 1)
+	assert.Equal(t, tx.ID, job.TransactionID)
+	assert.Equal(t, uint(1), job.ClientID)
+	// Nadie lo tiene apartado: la cola lo toma de inmediato
+	assert.Nil(t, job.LockedUntil)
+	connector.AssertNotCalled(t, "NotifyPayment", mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "SaveTransaction", mock.Anything)
+}
+
+// queuedPayment configura el mock con un job apartado para un pago PENDING
+func queuedPayment(mockRepo *MockRepo, attempts int) (*domain.PaymentJob, *domain.Transaction, *[]*domain.JournalEntry) {
+	var entries []*domain.JournalEntry
+	tx := &domain.Transaction{ID: uuid.New(), Amount: domain.MustParseMoney("150.00"), Currency: "MXN", MerchantID: 1, ClientID: 7, Reference: "CFE-1", Status: "PENDING"}
+	job := &domain.PaymentJob{ID: 3, TransactionID: tx.ID, ClientID: 7, Attempts: attempts}
+	mockRepo.On("ClaimPaymentJob", mock.Anything, paymentJobLease).Return(job, nil)
+	mockRepo.On("GetTransaction", tx.ID, uint(7)).Return(tx, nil)
+	mockRepo.On("GetMerchantByID", uint(1)).Return(&domain.Merchant{ID: 1, Name: "CFE"}, nil)
+	mockRepo.On("SaveTransaction", mock.Anything).Return(nil)
+	mockRepo.On("PostJournalEntry", mock.Anything).Run(func(args mock.Arguments) {
+		entries = append(entries, args.Get(0).(*domain.JournalEntry))
+	}).Return(nil)
+	return job, tx, &entries
+}
+
+func TestProcessQueuedPayment_ApprovedCompletesAndNotifiesClient(t *testing.T) {
+	mockRepo := new(MockRepo)
+	notifier := new(MockNotifier)
+	service := NewPaymentService(mockRepo, nil, NewLimitChecker(time.UTC), approvingConnector(), nil, nil, notifier, nil)
+	job, tx, _ := queuedPayment(mockRepo, 1)
+	client := &domain.Client{ID: 7, WebhookURL: "https://cliente.example/webhook"}
+	mockRepo.On("DeletePaymentJob", job.ID).Return(nil)
+	mockRepo.On("GetClientByID", uint(7)).Return(client, nil)
+	notifier.On("NotifyTransaction", client, tx).Return(nil)
+
+	found, err := service.ProcessQueuedPayment()
+
+	assert.True(t, found)
+	assert.NoError(t, err)
+	assert.Equal(t, "COMPLETED", tx.Status)
+	assert.Equal(t, "AUT-1", tx.AuthorizationNumber)
+	mockRepo.AssertCalled(t, "DeletePaymentJob", job.ID)
+	notifier.AssertExpectations(t)
+}
+
+func TestProcessQueuedPayment_BillerUnavailableRetriesLater(t *testing.T) {
+	mockRepo := new(MockRepo)
+	connector := new(MockConnector)
+	service := NewPaymentService(mockRepo, nil, NewLimitChecker(time.UTC), connector, nil, nil, nil, nil).(*paymentService)
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+	job, tx, entries := queuedPayment(mockRepo, 2)
+	connector.On("NotifyPayment", mock.Anything, tx).Return(nil, errors.New("CFE no contestó: timeout"))
+	mockRepo.On("SavePaymentJob", job).Return(nil)
+
+	found, err := service.ProcessQueuedPayment()
+
+	assert.True(t, found)
+	assert.NoError(t, err)
+	// Segundo intento: la espera ya se duplicó
+	assert.Equal(t, now.Add(2*paymentRetryDelay), job.RunAt)
+	assert.Nil(t, job.LockedUntil)
+	assert.Contains(t, job.LastError, "timeout")
+	// El pago sigue pendiente y con su cargo
+	assert.Equal(t, "PENDING", tx.Status)
+	assert.Empty(t, *entries)
+	mockRepo.AssertNotCalled(t, "DeletePaymentJob", mock.Anything)
+}
+
+func TestProcessQueuedPayment_NeverFailsWithoutAnAnswer(t *testing.T) {
+	mockRepo := new(MockRepo)
+	connector := new(MockConnector)
+	service := NewPaymentService(mockRepo, nil, NewLimitChecker(time.UTC), connector, nil, nil, nil, nil).(*paymentService)
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+	job, tx, entries := queuedPayment(mockRepo, 20)
+	connector.On("NotifyPayment", mock.Anything, tx).Return(nil, errors.New("CFE no contestó: timeout"))
+	mockRepo.On("SavePaymentJob", job).Return(nil)
+
+	found, err := service.ProcessQueuedPayment()
+
+	// El biller pudo haberlo aplicado: se sigue preguntando, con la espera topada
+	assert.True(t, found)
+	assert.NoError(t, err)
+	assert.Equal(t, "PENDING", tx.Status)
+	assert.Empty(t, *entries)
+	assert.Equal(t, now.Add(paymentMaxRetryDelay), job.RunAt)
+	mockRepo.AssertNotCalled(t, "DeletePaymentJob", mock.Anything)
+}
+
+func TestProcessQueuedPayment_RejectedFailsAndReverses(t *testing.T) {
+	mockRepo := new(MockRepo)
+	connector := new(MockConnector)
+	service := NewPaymentService(mockRepo, nil, NewLimitChecker(time.UTC), connector, nil, nil, nil, nil)
+	job, tx, entries := queuedPayment(mockRepo, 2)
+	connector.On("NotifyPayment", mock.Anything, tx).Return(&domain.PaymentConfirmation{Approved: false, Message: "referencia vencida"}, nil)
+	mockRepo.On("DeletePaymentJob", job.ID).Return(nil)
+
+	found, err := service.ProcessQueuedPayment()
+
+	// El rechazo es el resultado del pago, no un error del worker
+	assert.True(t, found)
+	assert.NoError(t, err)
+	assert.Equal(t, "FAILED", tx.Status)
+	assert.Len(t, *entries, 1)
+	assert.Equal(t, domain.OperationVoid, (*entries)[0].OperationType)
+}
+
+func TestProcessQueuedPayment_EmptyQueue(t *testing.T) {
+	mockRepo := new(MockRepo)
+	service := NewPaymentService(mockRepo, nil, NewLimitChecker(time.UTC), new(MockConnector), nil, nil, nil, nil)
+	mockRepo.On("ClaimPaymentJob", mock.Anything, paymentJobLease).Return(nil, nil)
+
+	found, err := service.ProcessQueuedPayment()
+
+	assert.False(t, found)
+	assert.NoError(t, err)
+}
@@ -1,77 +1,258 @@
 package services
 
 import (
-	// Importante añadir esto
-	"encoding/json"
 	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/scorazag/gopayhub/internal/core/domain"
 	"github.com/scorazag/gopayhub/internal/core/ports"
 )
 
 type paymentService struct {
-	repo ports.PaymentRepository // Aquí guardamos la interfaz
+	repo       ports.PaymentRepository // Aquí guardamos la interfaz
+	fx         ports.FXRateProvider    // Tipo de cambio para merchants que cobran en otra moneda
+	limits     *LimitChecker
+	connector  ports.MerchantConnector // Le avisa al biller para que aplique el pago
+	bills      ports.BillCache         // Consultas de recibos con las que se puede cobrar
+	references *ReferenceValidator
+	notifier   ports.ClientNotifier   // Avisa al cliente cuando termina un pago asíncrono; opcional
+	circuits   ports.MerchantCircuits // Circuit breaker de los billers; opcional
+	now        func() time.Time
 }
 
 // Constructor del servicio
-func NewPaymentService(repo ports.PaymentRepository) ports.PaymentService {
-	return &paymentService{repo: repo}
+func NewPaymentService(repo ports.PaymentRepository, fx ports.FXRateProvider, limits *LimitChecker, connector ports.MerchantConnector, bills ports.BillCache, references *ReferenceValidator, notifier ports.ClientNotifier, circuits ports.MerchantCircuits) ports.PaymentService {
+	return &paymentService{repo: repo, fx: fx, limits: limits, connector: connector, bills: bills, references: references, notifier: notifier, circuits: circuits, now: time.Now}
 }
 
-func (s *paymentService) ProcessPayment(amount float64, merchantID uint, clientID uint, reference string, idemKey string) (*domain.Transaction, error) {
-
-	// 0. BUSCAR IDEMPOTENCIA
-	if idemKey != "" {
-		existingKey, err := s.repo.GetIdempotencyKey(idemKey)
-		// Si no hay error y encontramos la llave...
-		if err == nil && existingKey != nil && existingKey.Key != "" {
-			var oldTx domain.Transaction
-			// Convertimos el JSON guardado en la DB de nuevo a un struct de Transacción
-			if err := json.Unmarshal([]byte(existingKey.ResponseJSON), &oldTx); err == nil {
-				return &oldTx, nil // Retornamos la transacción original sin hacer nada más
+func (s *paymentService) ProcessPayment(amount domain.Money, currency string, merchantID uint, clientID uint, reference string, billInquiryID uuid.UUID, idemKey string) (*domain.Transaction, error) {
+	tx, merchant, job, err := s.createPending(amount, currency, merchantID, clientID, reference, billInquiryID, idemKey, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// 6. AVISAR AL BILLER (fuera de la transacción de BD para no retener el bloqueo durante la llamada)
+	return s.confirm(tx, merchant, job)
+}
+
+// confirm le avisa al biller de un pago ya cobrado y guarda su respuesta. El cargo ya está guardado
+// con su job apartado: pase lo que pase se regresa la transacción, y si no se pudo terminar aquí la
+// termina la cola (el cliente la ve PENDING como en el modo asíncrono). Solo el rechazo es error.
+func (s *paymentService) confirm(tx *domain.Transaction, merchant *domain.Merchant, job *domain.PaymentJob) (*domain.Transaction, error) {
+	confirmation, err := s.connector.NotifyPayment(merchant, tx)
+	if err != nil {
+		// Sin respuesta no sabemos si el biller lo aplicó: no se reversa
+		if err := s.retryLater(job, err); err != nil {
+			log.Printf("[pagos] la transacción %s queda apartada hasta que venza su job: %v", tx.ID, err)
+		}
+		return tx, nil
+	}
+	if err := s.settle(tx, confirmation, job); err != nil {
+		var coded domain.CodedError
+		if errors.As(err, &coded) {
+			return nil, err
+		}
+		// El biller contestó pero no se pudo guardar: el job sigue ahí y la cola le vuelve a preguntar
+		log.Printf("[pagos] no se pudo guardar la respuesta del biller para la transacción %s: %v", tx.ID, err)
+		tx.Status, tx.AuthorizationNumber, tx.FailureReason = "PENDING", "", ""
+	}
+	return tx, nil
+}
+
+// ProcessPaymentAsync hace lo mismo que ProcessPayment pero el aviso al biller queda en la cola;
+// el cliente consulta la transacción (o recibe su webhook) para saber cómo terminó
+func (s *paymentService) ProcessPaymentAsync(amount domain.Money, currency string, merchantID uint, clientID uint, reference string, billInquiryID uuid.UUID, idemKey string) (*domain.Transaction, error) {
+	tx, _, _, err := s.createPending(amount, currency, merchantID, clientID, reference, billInquiryID, idemKey, true)
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// createPending valida el pago, cobra al cliente y guarda la transacción en PENDING.
+// En la misma transacción de BD se encola el aviso al biller; con async lo hace la cola desde el principio.
+func (s *paymentService) createPending(amount domain.Money, currency string, merchantID uint, clientID uint, reference string, billInquiryID uuid.UUID, idemKey string, async bool) (*domain.Transaction, *domain.Merchant, *domain.PaymentJob, error) {
+	// Los reintentos con la misma llave los contesta el middleware de idempotencia; aquí solo la guardamos en la transacción
+
+	// 0. SI SE CONSULTÓ EL RECIBO, SE APARTA LA CONSULTA PARA QUE NO SE COBRE DOS VECES
+	if billInquiryID == uuid.Nil {
+		return s.charge(amount, currency, merchantID, clientID, reference, nil, idemKey, async)
+	}
+	bill, ok := s.bills.Take(billInquiryID, clientID)
+	if !ok {
+		return nil, nil, nil, domain.ErrBillInquiryExpired
+	}
+	tx, merchant, job, err := s.charge(amount, currency, merchantID, clientID, reference, bill, idemKey, async)
+	if err != nil {
+		// No se cobró: la consulta se puede volver a usar mientras siga vigente
+		s.bills.Put(bill)
+	}
+	return tx, merchant, job, err
+}
+
+// charge hace el cobro de createPending; con bill, lo que falte se toma de la consulta del recibo
+func (s *paymentService) charge(amount domain.Money, currency string, merchantID uint, clientID uint, reference string, bill *domain.Bill, idemKey string, async bool) (*domain.Transaction, *domain.Merchant, *domain.PaymentJob, error) {
+	var inquiryID *uuid.UUID
+	if bill != nil {
+		if bill.MerchantID != merchantID || (reference != "" && reference != bill.Reference) {
+			return nil, nil, nil, fmt.Errorf("%w: la consulta es de la referencia %s del merchant %d", domain.ErrBillInquiryMismatch, bill.Reference, bill.MerchantID)
+		}
+		reference = bill.Reference
+		// Sin monto se paga lo que se debe, en la moneda del recibo
+		if amount == 0 {
+			if currency != "" && !strings.EqualFold(currency, bill.Currency) {
+				return nil, nil, nil, fmt.Errorf("%w: sin monto se paga en %s, la moneda del recibo", domain.ErrBillInquiryMismatch, bill.Currency)
 			}
+			amount, currency = bill.AmountDue, bill.Currency
 		}
+		inquiryID = &bill.InquiryID
 	}
 
 	// 1. REGLAS DE NEGOCIO
 	if amount <= 0 {
-		return nil, errors.New("el monto debe ser mayor a cero")
+		return nil, nil, nil, domain.ErrInvalidAmount
+	}
+	currency, err := domain.NormalizeCurrency(currency)
+	if err != nil {
+		return nil, nil, nil, err
 	}
 
 	// 2. VERIFICAR MERCHANT
 	merchant, err := s.repo.GetMerchantByID(merchantID)
 	if err != nil {
-		return nil, errors.New("proveedor de servicio no encontrado")
+		return nil, nil, nil, notFoundAs(err, domain.ErrMerchantNotFound)
+	}
+	// Si aplica, el tipo de cambio va primero: lo que dice la referencia está en la moneda del merchant
+	conversion, err := convertForMerchant(s.fx, amount, currency, merchant)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	// Una referencia mal capturada se rechaza aquí y no cuando el biller la rechace
+	if err := s.references.Validate(merchant, reference, merchantAmount(amount, conversion)); err != nil {
+		return nil, nil, nil, err
+	}
+	// Si el biller está caído no tiene caso cobrarle al cliente para luego reversarlo
+	if err := s.available(merchant); err != nil {
+		return nil, nil, nil, err
+	}
+
+	// 3. CALCULAR COMISIONES
+	fees, err := calculateFees(s.repo, domain.OperationPayment, currency, clientID, merchant, amount)
+	if err != nil {
+		return nil, nil, nil, err
 	}
 
-	// 3. CREAR OBJETO TRANSACCIÓN
+	// 4. CREAR OBJETO TRANSACCIÓN
 	tx := &domain.Transaction{
 		Amount:         amount,
+		Currency:       currency,
+		FeeAmount:      domain.TotalFees(fees),
+		Fees:           fees,
+		FXConversion:   conversion,
 		MerchantID:     merchant.ID,
 		ClientID:       clientID,
 		Reference:      reference,
-		Status:         "COMPLETED",
+		Status:         "PENDING", // Hasta que el biller conteste
 		IdempotencyKey: idemKey,
+		BillInquiryID:  inquiryID,
 	}
 
-	// 4. GUARDAR TRANSACCIÓN PRIMERO
-	if err := s.repo.CreateTransaction(tx); err != nil {
-		return nil, err
+	// 5. VALIDAR LÍMITES Y GUARDAR TRANSACCIÓN, SU PÓLIZA Y SU JOB
+	// El cargo queda aplicado mientras el biller contesta, así el saldo no se puede gastar dos veces.
+	// El job se guarda junto con el cargo: si la petición se cae a medias, la cola termina el pago.
+	// En modo síncrono queda apartado por esta petición, que es la que le pregunta al biller.
+	job := &domain.PaymentJob{ClientID: clientID, RunAt: s.now()}
+	if !async {
+		job = s.leasedJob(clientID)
 	}
+	err = s.repo.Atomic(func(repo ports.PaymentRepository) error {
+		if err := repo.LockClientAccount(clientID, currency); err != nil {
+			return err
+		}
+		// Las retenciones de pagos autorizados tampoco se pueden gastar
+		available, err := availableBalance(repo, clientID, currency)
+		if err != nil {
+			return err
+		}
+		if amount+tx.FeeAmount > available {
+			return domain.ErrInsufficientFunds
+		}
+		if err := s.limits.Check(repo, paymentLimitOperation(tx)); err != nil {
+			return err
+		}
+		if err := repo.CreateTransaction(tx); err != nil {
+			return err
+		}
+		job.TransactionID = tx.ID
+		if err := repo.PostJournalEntry(domain.NewPaymentEntry(tx)); err != nil {
+			return err
+		}
+		return repo.EnqueuePaymentJob(job)
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return tx, merchant, job, nil
+}
 
-	// 5. GUARDAR LLAVE DE IDEMPOTENCIA CON EL RESULTADO
-	if idemKey != "" {
-		// Convertimos la transacción real (ya con su ID y fecha) a JSON
-		txJSON, _ := json.Marshal(tx)
-
-		newIdem := &domain.IdempotencyKey{
-			Key:          idemKey,
-			ResponseJSON: string(txJSON),
-			StatusCode:   201,
+// settle guarda la respuesta del biller: COMPLETED con su folio, o FAILED reversando el cargo si lo rechazó.
+// Solo se llama con una respuesta: si el biller no contestó pudo haberlo aplicado, y el pago se queda PENDING.
+// Regresa el error de negocio que ve el cliente. El job del pago se borra en la misma transacción de BD.
+func (s *paymentService) settle(tx *domain.Transaction, confirmation *domain.PaymentConfirmation, job *domain.PaymentJob) error {
+	var result error
+	err := s.repo.Atomic(func(repo ports.PaymentRepository) error {
+		if err := repo.DeletePaymentJob(job.ID); err != nil {
+			return err
 		}
-		// Guardamos la llave para futuros reintentos
-		_ = s.repo.SaveIdempotencyKey(newIdem)
+		if !confirmation.Approved {
+			result = fmt.Errorf("%w: %s", domain.ErrPaymentRejected, confirmation.Message)
+			return failPayment(repo, tx, confirmation.Message)
+		}
+		tx.Status = "COMPLETED"
+		tx.AuthorizationNumber = confirmation.AuthorizationNumber
+		return repo.SaveTransaction(tx)
+	})
+	if err != nil {
+		return err
 	}
+	return result
+}
 
-	return tx, nil
+// leasedJob es el job de un pago al que esta misma petición le va a preguntar al biller: queda
+// apartado para que ningún worker lo tome mientras tanto, y si el proceso se cae, lo retoma al vencer
+func (s *paymentService) leasedJob(clientID uint) *domain.PaymentJob {
+	lockedUntil := s.now().Add(paymentJobLease)
+	return &domain.PaymentJob{ClientID: clientID, Attempts: 1, RunAt: s.now(), LockedUntil: &lockedUntil}
+}
+
+// available consulta el circuit breaker del biller, si lo hay
+func (s *paymentService) available(merchant *domain.Merchant) error {
+	if s.circuits == nil {
+		return nil
+	}
+	return s.circuits.Available(merchant)
+}
+
+// failPayment marca el pago como FAILED y reversa su póliza (monto y comisiones); usar dentro de Atomic
+func failPayment(repo ports.PaymentRepository, tx *domain.Transaction, reason string) error {
+	tx.Status = "FAILED"
+	tx.FailureReason = truncate(reason, 200)
+	if err := repo.SaveTransaction(tx); err != nil {
+		return err
+	}
+	entry := domain.NewVoidEntry(domain.NewPaymentEntry(tx))
+	entry.Description = "Pago no aplicado por el biller: " + tx.Reference
+	return repo.PostJournalEntry(entry)
+}
+
+// truncate recorta s a max caracteres para que quepa en su columna
+func truncate(s string, max int) string {
+	if r := []rune(s); len(r) > max {
+		return string(r[:max])
+	}
+	return s
 }
@@ -1,10 +1,14 @@
 package services
 
 import (
-	"encoding/json"
+	"errors"
+	"fmt"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/scorazag/gopayhub/internal/core/ports"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -26,6 +30,131 @@ func (m *MockRepo) CreateTransaction(tx *domain.Transaction) error {
 	return m.Called(tx).Error(0)
 }
 
+func (m *MockRepo) GetTransactionForUpdate(id uuid.UUID) (*domain.Transaction, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Transaction), args.Error(1)
+}
+
+func (m *MockRepo) GetTransaction(id uuid.UUID, clientID uint) (*domain.Transaction, error) {
+	args := m.Called(id, clientID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Transaction), args.Error(1)
+}
+
+func (m *MockRepo) GetDeposit(id uuid.UUID, clientID uint) (*domain.Deposit, error) {
+	args := m.Called(id, clientID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Deposit), args.Error(1)
+}
+
+func (m *MockRepo) GetCashOut(id uuid.UUID, clientID uint) (*domain.CashOut, error) {
+	args := m.Called(id, clientID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.CashOut), args.Error(1)
+}
+
+func (m *MockRepo) GetTransactionByIdempotencyKey(key string, clientID uint) (*domain.Transaction, error) {
+	args := m.Called(key, clientID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Transaction), args.Error(1)
+}
+
+func (m *MockRepo) GetDepositByIdempotencyKey(key string, clientID uint) (*domain.Deposit, error) {
+	args := m.Called(key, clientID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Deposit), args.Error(1)
+}
+
+func (m *MockRepo) GetCashOutByIdempotencyKey(key string, clientID uint) (*domain.CashOut, error) {
+	args := m.Called(key, clientID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.CashOut), args.Error(1)
+}
+
+func (m *MockRepo) ListTransactions(f domain.OperationFilter) ([]domain.Transaction, error) {
+	args := m.Called(f)
+	return args.Get(0).([]domain.Transaction), args.Error(1)
+}
+
+func (m *MockRepo) ListDeposits(f domain.OperationFilter) ([]domain.Deposit, error) {
+	args := m.Called(f)
+	return args.Get(0).([]domain.Deposit), args.Error(1)
+}
+
+func (m *MockRepo) ListCashOuts(f domain.OperationFilter) ([]domain.CashOut, error) {
+	args := m.Called(f)
+	return args.Get(0).([]domain.CashOut), args.Error(1)
+}
+
+func (m *MockRepo) GetClientBalanceBefore(clientID uint, currency string, at domain.PageCursor) (domain.Money, error) {
+	args := m.Called(clientID, currency, at)
+	return args.Get(0).(domain.Money), args.Error(1)
+}
+
+func (m *MockRepo) ListStatementLines(q domain.StatementQuery) ([]domain.StatementLine, error) {
+	args := m.Called(q)
+	return args.Get(0).([]domain.StatementLine), args.Error(1)
+}
+
+func (m *MockRepo) SaveTransaction(tx *domain.Transaction) error {
+	return m.Called(tx).Error(0)
+}
+
+func (m *MockRepo) CreateRefund(refund *domain.Refund) error {
+	return m.Called(refund).Error(0)
+}
+
+func (m *MockRepo) SaveRefund(refund *domain.Refund) error {
+	return m.Called(refund).Error(0)
+}
+
+func (m *MockRepo) GetRefund(id uuid.UUID) (*domain.Refund, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Refund), args.Error(1)
+}
+
+func (m *MockRepo) GetDepositForUpdate(id uuid.UUID) (*domain.Deposit, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Deposit), args.Error(1)
+}
+
+func (m *MockRepo) SaveDeposit(deposit *domain.Deposit) error {
+	return m.Called(deposit).Error(0)
+}
+
+func (m *MockRepo) GetCashOutForUpdate(id uuid.UUID) (*domain.CashOut, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.CashOut), args.Error(1)
+}
+
+func (m *MockRepo) SaveCashOut(cashout *domain.CashOut) error {
+	return m.Called(cashout).Error(0)
+}
+
 func (m *MockRepo) GetClientByApiKey(apiKey string) (*domain.Client, error) {
 	args := m.Called(apiKey)
 	if args.Get(0) == nil {
@@ -34,8 +163,24 @@ func (m *MockRepo) GetClientByApiKey(apiKey string) (*domain.Client, error) {
 	return args.Get(0).(*domain.Client), args.Error(1)
 }
 
-func (m *MockRepo) GetIdempotencyKey(key string) (*domain.IdempotencyKey, error) {
-	args := m.Called(key)
+func (m *MockRepo) GetClientByID(id uint) (*domain.Client, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Client), args.Error(1)
+}
+
+func (m *MockRepo) GetTaxRate(region string) (*domain.TaxRate, error) {
+	args := m.Called(region)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.TaxRate), args.Error(1)
+}
+
+func (m *MockRepo) GetIdempotencyKey(scope domain.IdempotencyScope) (*domain.IdempotencyKey, error) {
+	args := m.Called(scope)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -46,6 +191,20 @@ func (m *MockRepo) SaveIdempotencyKey(key *domain.IdempotencyKey) error {
 	return m.Called(key).Error(0)
 }
 
+func (m *MockRepo) ClaimIdempotencyKey(key *domain.IdempotencyKey) (bool, error) {
+	args := m.Called(key)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) DeleteIdempotencyKey(scope domain.IdempotencyScope) error {
+	return m.Called(scope).Error(0)
+}
+
+func (m *MockRepo) PurgeExpiredIdempotencyKeys(now time.Time) (int64, error) {
+	args := m.Called(now)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 func (m *MockRepo) CreateDeposit(deposit *domain.Deposit) error {
 	args := m.Called(deposit)
 	return args.Error(0)
@@ -56,79 +215,372 @@ func (m *MockRepo) CreateCashOut(cashout *domain.CashOut) error {
 	return args.Error(0)
 }
 
-func (m *MockRepo) GetClientBalance(clientID uint) (float64, error) {
-	args := m.Called(clientID)
-	return args.Get(0).(float64), args.Error(1)
+func (m *MockRepo) GetClientBalance(clientID uint, currency string) (domain.Money, error) {
+	args := m.Called(clientID, currency)
+	return args.Get(0).(domain.Money), args.Error(1)
+}
+
+func (m *MockRepo) GetClientHolds(clientID uint, currency string) (domain.Money, error) {
+	args := m.Called(clientID, currency)
+	return args.Get(0).(domain.Money), args.Error(1)
+}
+
+// funded le da al cliente saldo de sobra en esa moneda, sin retenciones
+func funded(m *MockRepo, currency string) {
+	m.On("GetClientBalance", uint(1), currency).Return(domain.MustParseMoney("100000"), nil)
+	m.On("GetClientHolds", uint(1), currency).Return(domain.Money(0), nil)
+}
+
+func (m *MockRepo) ReleaseExpiredHolds(now time.Time) (int64, error) {
+	args := m.Called(now)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepo) FindFeeSchedules(operationType string, currency string, clientID uint, merchantID uint, serviceType string) ([]domain.FeeSchedule, error) {
+	args := m.Called(operationType, currency, clientID, merchantID, serviceType)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.FeeSchedule), args.Error(1)
+}
+
+// noFees configura el mock para que ninguna operación tenga comisión
+func noFees(m *MockRepo) {
+	m.On("FindFeeSchedules", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+}
+
+func (m *MockRepo) FindLimits(op domain.LimitedOperation) ([]domain.Limit, error) {
+	args := m.Called(op)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Limit), args.Error(1)
+}
+
+func (m *MockRepo) SumOperations(q domain.LimitUsageQuery) (domain.Money, error) {
+	args := m.Called(q)
+	return args.Get(0).(domain.Money), args.Error(1)
+}
+
+func (m *MockRepo) LockLimit(id uint) error {
+	return m.Called(id).Error(0)
+}
+
+// noLimits configura el mock para que no haya límites configurados
+func noLimits(m *MockRepo) {
+	m.On("FindLimits", mock.Anything).Return(nil, nil).Maybe()
+}
+
+func (m *MockRepo) PostJournalEntry(entry *domain.JournalEntry) error {
+	return m.Called(entry).Error(0)
+}
+
+func (m *MockRepo) LockClientAccount(clientID uint, currency string) error {
+	return m.Called(clientID, currency).Error(0)
+}
+
+func (m *MockRepo) EnqueuePaymentJob(job *domain.PaymentJob) error {
+	return m.Called(job).Error(0)
+}
+
+func (m *MockRepo) ClaimPaymentJob(now time.Time, lease time.Duration) (*domain.PaymentJob, error) {
+	args := m.Called(now, lease)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.PaymentJob), args.Error(1)
+}
+
+func (m *MockRepo) SavePaymentJob(job *domain.PaymentJob) error {
+	return m.Called(job).Error(0)
+}
+
+func (m *MockRepo) DeletePaymentJob(id uint) error {
+	return m.Called(id).Error(0)
+}
+
+func (m *MockRepo) EnqueueRefundJob(job *domain.RefundJob) error {
+	return m.Called(job).Error(0)
+}
+
+func (m *MockRepo) ClaimRefundJob(now time.Time, lease time.Duration) (*domain.RefundJob, error) {
+	args := m.Called(now, lease)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.RefundJob), args.Error(1)
+}
+
+func (m *MockRepo) SaveRefundJob(job *domain.RefundJob) error {
+	return m.Called(job).Error(0)
+}
+
+func (m *MockRepo) DeleteRefundJob(id uint) error {
+	return m.Called(id).Error(0)
+}
+
+// Atomic no abre transacción en el mock: ejecuta fn con el mismo repo
+func (m *MockRepo) Atomic(fn func(repo ports.PaymentRepository) error) error {
+	return fn(m)
+}
+
+// approvingConnector es un biller que aprueba todos los pagos con el folio "AUT-1"
+func approvingConnector() *MockConnector {
+	c := new(MockConnector)
+	c.On("NotifyPayment", mock.Anything, mock.Anything).Return(&domain.PaymentConfirmation{Approved: true, AuthorizationNumber: "AUT-1"}, nil).Maybe()
+	return c
 }
 
 // --- TEST 1: MONTO CERO ---
 func TestProcessPayment_AmountZero(t *testing.T) {
 	mockRepo := new(MockRepo)
-	service := NewPaymentService(mockRepo)
+	service := NewPaymentService(mockRepo, nil, NewLimitChecker(time.UTC), approvingConnector(), nil, nil, nil, nil)
 
 	// No necesitamos configurar mocks aquí porque el código falla ANTES de tocar el repo
-	tx, err := service.ProcessPayment(0, 1, 1, "REF-123", "")
+	tx, err := service.ProcessPayment(0, "MXN", 1, 1, "REF-123", uuid.Nil, "")
 
 	assert.Nil(t, tx)
 	assert.Equal(t, "el monto debe ser mayor a cero", err.Error())
 }
 
-// --- TEST 2: IDEMPOTENCIA (Llave existente) ---
-func TestProcessPayment_IdempotencyHit(t *testing.T) {
+func TestProcessPayment_SuccessNewKey(t *testing.T) {
 	mockRepo := new(MockRepo)
-	service := NewPaymentService(mockRepo)
+	service := NewPaymentService(mockRepo, nil, NewLimitChecker(time.UTC), approvingConnector(), nil, nil, nil, nil)
 
-	// Preparamos una transacción vieja "guardada" en JSON
-	oldTx := domain.Transaction{Amount: 100, Reference: "PAGO-ANTERIOR"}
-	oldTxJSON, _ := json.Marshal(oldTx)
+	merchant := &domain.Merchant{ID: 1, Name: "Test Merchant"}
+	idemKey := "nueva-llave-123"
 
-	existingKey := &domain.IdempotencyKey{
-		Key:          "key-repetida",
-		ResponseJSON: string(oldTxJSON),
-	}
+	// 1. Mock: El merchant existe
+	mockRepo.On("GetMerchantByID", uint(1)).Return(merchant, nil)
 
-	// Configuramos el mock: "Cuando pregunten por esta llave, devuélvela"
-	mockRepo.On("GetIdempotencyKey", "key-repetida").Return(existingKey, nil)
+	noFees(mockRepo)
+	noLimits(mockRepo)
+
+	// 2. Mock: Se crea la transacción (usamos Anything porque el UUID se genera adentro)
+	mockRepo.On("LockClientAccount", uint(1), "MXN").Return(nil)
+	funded(mockRepo, "MXN")
+	mockRepo.On("CreateTransaction", mock.Anything).Return(nil)
+	paymentJobs(mockRepo)
+	mockRepo.On("SaveTransaction", mock.Anything).Return(nil)
+	mockRepo.On("PostJournalEntry", mock.Anything).Return(nil)
 
 	// Ejecución
-	tx, err := service.ProcessPayment(100, 1, 1, "REF-123", "key-repetida")
+	tx, err := service.ProcessPayment(domain.MustParseMoney("150.00"), "MXN", 1, 1, "REF-ABC", uuid.Nil, idemKey)
 
 	// Aserciones
 	assert.NoError(t, err)
 	assert.NotNil(t, tx)
-	assert.Equal(t, "PAGO-ANTERIOR", tx.Reference)
+	assert.Equal(t, domain.MustParseMoney("150.00"), tx.Amount)
+	// La llave queda ligada a la transacción; la respuesta la guarda el middleware
+	assert.Equal(t, idemKey, tx.IdempotencyKey)
+	// El biller lo aplicó y su folio queda guardado
+	assert.Equal(t, "COMPLETED", tx.Status)
+	assert.Equal(t, "AUT-1", tx.AuthorizationNumber)
+
+	// Verificamos que se llamaron a los métodos de guardado
+	mockRepo.AssertExpectations(t)
+}
+
+func TestProcessPayment_MerchantNotFoundVsDatabaseError(t *testing.T) {
+	mockRepo := new(MockRepo)
+	service := NewPaymentService(mockRepo, nil, NewLimitChecker(time.UTC), approvingConnector(), nil, nil, nil, nil)
+	outage := errors.New("dial tcp: connection refused")
+	mockRepo.On("GetMerchantByID", uint(1)).Return(nil, domain.ErrNotFound)
+	mockRepo.On("GetMerchantByID", uint(2)).Return(nil, outage)
+
+	_, err := service.ProcessPayment(domain.MustParseMoney("150.00"), "MXN", 1, 1, "REF-1", uuid.Nil, "")
+	assert.ErrorIs(t, err, domain.ErrMerchantNotFound)
 
-	// Verificamos que NO se intentó crear una nueva transacción
+	// Una caída de la BD no se disfraza de merchant inexistente
+	_, err = service.ProcessPayment(domain.MustParseMoney("150.00"), "MXN", 2, 1, "REF-2", uuid.Nil, "")
+	assert.ErrorIs(t, err, outage)
+	assert.NotErrorIs(t, err, domain.ErrMerchantNotFound)
+}
+
+func TestProcessPayment_InsufficientFunds(t *testing.T) {
+	mockRepo := new(MockRepo)
+	connector := new(MockConnector)
+	service := NewPaymentService(mockRepo, nil, NewLimitChecker(time.UTC), connector, nil, nil, nil, nil)
+
+	mockRepo.On("GetMerchantByID", uint(1)).Return(&domain.Merchant{ID: 1, Name: "CFE"}, nil)
+	noFees(mockRepo)
+	noLimits(mockRepo)
+	mockRepo.On("LockClientAccount", uint(1), "MXN").Return(nil)
+	// Saldo de $1,000 con $900 retenidos por autorizaciones: solo quedan $100 disponibles
+	mockRepo.On("GetClientBalance", uint(1), "MXN").Return(domain.MustParseMoney("1000"), nil)
+	mockRepo.On("GetClientHolds", uint(1), "MXN").Return(domain.MustParseMoney("900"), nil)
+
+	tx, err := service.ProcessPayment(domain.MustParseMoney("100.01"), "MXN", 1, 1, "CFE-1", uuid.Nil, "")
+
+	assert.Nil(t, tx)
+	assert.ErrorIs(t, err, domain.ErrInsufficientFunds)
 	mockRepo.AssertNotCalled(t, "CreateTransaction", mock.Anything)
+	mockRepo.AssertNotCalled(t, "EnqueuePaymentJob", mock.Anything)
+	connector.AssertNotCalled(t, "NotifyPayment", mock.Anything, mock.Anything)
 }
 
-func TestProcessPayment_SuccessNewKey(t *testing.T) {
+// pendingPayment configura el mock para que el pago se guarde como PENDING y regresa las pólizas que se registren
+func pendingPayment(mockRepo *MockRepo) *[]*domain.JournalEntry {
+	var entries []*domain.JournalEntry
+	mockRepo.On("GetMerchantByID", uint(1)).Return(&domain.Merchant{ID: 1, Name: "CFE"}, nil)
+	noFees(mockRepo)
+	noLimits(mockRepo)
+	mockRepo.On("LockClientAccount", uint(1), "MXN").Return(nil)
+	funded(mockRepo, "MXN")
+	mockRepo.On("CreateTransaction", mock.MatchedBy(func(tx *domain.Transaction) bool {
+		return tx.Status == "PENDING"
+	})).Return(nil)
+	mockRepo.On("PostJournalEntry", mock.Anything).Run(func(args mock.Arguments) {
+		entries = append(entries, args.Get(0).(*domain.JournalEntry))
+	}).Return(nil)
+	mockRepo.On("SaveTransaction", mock.Anything).Return(nil)
+	paymentJobs(mockRepo)
+	return &entries
+}
+
+// paymentJobs acepta el job que se guarda con cada pago; la BD le pone el ID 3
+func paymentJobs(mockRepo *MockRepo) {
+	mockRepo.On("EnqueuePaymentJob", mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(0).(*domain.PaymentJob).ID = 3
+	}).Return(nil)
+	mockRepo.On("SavePaymentJob", mock.Anything).Return(nil).Maybe()
+	mockRepo.On("DeletePaymentJob", uint(3)).Return(nil).Maybe()
+}
+
+// enqueuedJob regresa el job que se guardó con el pago
+func enqueuedJob(t *testing.T, mockRepo *MockRepo) *domain.PaymentJob {
+	for _, call := range mockRepo.Calls {
+		if call.Method == "EnqueuePaymentJob" {
+			return call.Arguments.Get(0).(*domain.PaymentJob)
+		}
+	}
+	t.Fatal("no se encoló el pago")
+	return nil
+}
+
+func TestProcessPayment_RejectedByBillerFailsAndReverses(t *testing.T) {
 	mockRepo := new(MockRepo)
-	service := NewPaymentService(mockRepo)
+	connector := new(MockConnector)
+	service := NewPaymentService(mockRepo, nil, NewLimitChecker(time.UTC), connector, nil, nil, nil, nil)
+	entries := pendingPayment(mockRepo)
+	connector.On("NotifyPayment", mock.Anything, mock.Anything).Return(&domain.PaymentConfirmation{Approved: false, Message: "referencia vencida"}, nil)
 
-	merchant := &domain.Merchant{ID: 1, Name: "Test Merchant"}
-	idemKey := "nueva-llave-123"
+	tx, err := service.ProcessPayment(domain.MustParseMoney("150.00"), "MXN", 1, 1, "CFE-VENCIDA", uuid.Nil, "")
 
-	// 1. Mock: No existe la llave todavía
-	mockRepo.On("GetIdempotencyKey", idemKey).Return(nil, nil)
+	assert.Nil(t, tx)
+	assert.ErrorIs(t, err, domain.ErrPaymentRejected)
+	assert.Contains(t, err.Error(), "referencia vencida")
 
-	// 2. Mock: El merchant existe
-	mockRepo.On("GetMerchantByID", uint(1)).Return(merchant, nil)
+	mockRepo.AssertCalled(t, "SaveTransaction", mock.MatchedBy(func(tx *domain.Transaction) bool {
+		return tx.Status == "FAILED" && tx.FailureReason == "referencia vencida" && tx.AuthorizationNumber == ""
+	}))
 
-	// 3. Mock: Se crea la transacción (usamos Anything porque el UUID se genera adentro)
-	mockRepo.On("CreateTransaction", mock.Anything).Return(nil)
+	// El cargo se reversa completo: el cliente recupera su saldo
+	assert.Len(t, *entries, 2)
+	reversal := (*entries)[1]
+	assert.Equal(t, domain.OperationVoid, reversal.OperationType)
+	assert.NoError(t, reversal.Validate())
+}
 
-	// 4. Mock: Se guarda la llave de idempotencia
-	mockRepo.On("SaveIdempotencyKey", mock.Anything).Return(nil)
+func TestProcessPayment_BillerUnavailableStaysPendingAndQueues(t *testing.T) {
+	mockRepo := new(MockRepo)
+	connector := new(MockConnector)
+	service := NewPaymentService(mockRepo, nil, NewLimitChecker(time.UTC), connector, nil, nil, nil, nil)
+	entries := pendingPayment(mockRepo)
+	connector.On("NotifyPayment", mock.Anything, mock.Anything).Return(nil, errors.New("CFE no contestó: timeout"))
 
-	// Ejecución
-	tx, err := service.ProcessPayment(150.0, 1, 1, "REF-ABC", idemKey)
+	tx, err := service.ProcessPayment(domain.MustParseMoney("150.00"), "MXN", 1, 1, "CFE-1", uuid.Nil, "")
+	job := enqueuedJob(t, mockRepo)
 
-	// Aserciones
+	// El biller pudo haberlo aplicado: no se reversa, la cola le vuelve a preguntar
 	assert.NoError(t, err)
-	assert.NotNil(t, tx)
-	assert.Equal(t, 150.0, tx.Amount)
+	assert.Equal(t, "PENDING", tx.Status)
+	assert.Len(t, *entries, 1)
+	mockRepo.AssertNotCalled(t, "SaveTransaction", mock.Anything)
+	assert.Equal(t, tx.ID, job.TransactionID)
+	assert.Equal(t, 1, job.Attempts)
+	assert.Contains(t, job.LastError, "timeout")
+	// Ya no está apartado por la petición: la cola lo toma después de la espera
+	assert.Nil(t, job.LockedUntil)
+	mockRepo.AssertCalled(t, "SavePaymentJob", job)
+	mockRepo.AssertNotCalled(t, "DeletePaymentJob", mock.Anything)
+}
 
-	// Verificamos que se llamaron a los métodos de guardado
-	mockRepo.AssertExpectations(t)
+func TestProcessPayment_JobIsSavedWithTheCharge(t *testing.T) {
+	mockRepo := new(MockRepo)
+	connector := new(MockConnector)
+	service := NewPaymentService(mockRepo, nil, NewLimitChecker(time.UTC), connector, nil, nil, nil, nil).(*paymentService)
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+	pendingPayment(mockRepo)
+	var job *domain.PaymentJob
+	connector.On("NotifyPayment", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		// Mientras se le pregunta al biller el job ya existe y está apartado por esta petición:
+		// si el proceso se cae aquí, un worker lo retoma cuando venza
+		job = enqueuedJob(t, mockRepo)
+		assert.Equal(t, now.Add(paymentJobLease), *job.LockedUntil)
+		mockRepo.AssertCalled(t, "CreateTransaction", mock.Anything)
+	}).Return(&domain.PaymentConfirmation{Approved: true, AuthorizationNumber: "AUT-1"}, nil)
+
+	tx, err := service.ProcessPayment(domain.MustParseMoney("150.00"), "MXN", 1, 1, "CFE-1", uuid.Nil, "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "COMPLETED", tx.Status)
+	// Con la respuesta guardada el job se borra
+	mockRepo.AssertCalled(t, "DeletePaymentJob", job.ID)
+}
+
+func TestProcessPayment_AnswerThatCannotBeSavedStaysPending(t *testing.T) {
+	mockRepo := new(MockRepo)
+	connector := new(MockConnector)
+	service := NewPaymentService(mockRepo, nil, NewLimitChecker(time.UTC), connector, nil, nil, nil, nil)
+	mockRepo.On("DeletePaymentJob", uint(3)).Return(errors.New("conexión perdida")).Once()
+	pendingPayment(mockRepo)
+	connector.On("NotifyPayment", mock.Anything, mock.Anything).Return(&domain.PaymentConfirmation{Approved: true, AuthorizationNumber: "AUT-1"}, nil)
+
+	tx, err := service.ProcessPayment(domain.MustParseMoney("150.00"), "MXN", 1, 1, "CFE-1", uuid.Nil, "")
+
+	// El cargo ya existe: el cliente recibe la transacción PENDING y la cola la termina
+	assert.NoError(t, err)
+	assert.Equal(t, "PENDING", tx.Status)
+	assert.Empty(t, tx.AuthorizationNumber)
+}
+
+func TestProcessPayment_InvalidReferenceRejectedBeforeCreating(t *testing.T) {
+	references, err := NewReferenceValidator(domain.ReferenceRules{
+		ByServiceType: map[string]domain.ReferenceRule{"TELEPHONE": {Name: "número de teléfono", MinLength: 10, MaxLength: 10, Charset: domain.CharsetDigits}},
+	}, time.UTC)
+	assert.NoError(t, err)
+	mockRepo := new(MockRepo)
+	connector := new(MockConnector)
+	service := NewPaymentService(mockRepo, nil, NewLimitChecker(time.UTC), connector, nil, references, nil, nil)
+	mockRepo.On("GetMerchantByID", uint(1)).Return(&domain.Merchant{ID: 1, ServiceType: "TELEPHONE"}, nil)
+
+	_, err = service.ProcessPayment(domain.MustParseMoney("100"), "MXN", 1, 1, "55-1234-5678", uuid.Nil, "")
+
+	assert.ErrorIs(t, err, domain.ErrInvalidReference)
+	assert.Contains(t, err.Error(), "número de teléfono")
+	mockRepo.AssertNotCalled(t, "CreateTransaction", mock.Anything)
+	connector.AssertNotCalled(t, "NotifyPayment", mock.Anything, mock.Anything)
+}
+
+type stubCircuits struct{ err error }
+
+func (c stubCircuits) Available(merchant *domain.Merchant) error { return c.err }
+func (c stubCircuits) Circuits() []domain.CircuitState           { return nil }
+
+func TestProcessPayment_OpenCircuitFailsFastWithoutCharging(t *testing.T) {
+	mockRepo := new(MockRepo)
+	connector := new(MockConnector)
+	circuits := stubCircuits{err: fmt.Errorf("%w: CFE falló 5 veces seguidas", domain.ErrMerchantUnavailable)}
+	service := NewPaymentService(mockRepo, nil, NewLimitChecker(time.UTC), connector, nil, nil, nil, circuits)
+	mockRepo.On("GetMerchantByID", uint(1)).Return(&domain.Merchant{ID: 1, Name: "CFE"}, nil)
+
+	tx, err := service.ProcessPayment(domain.MustParseMoney("150.00"), "MXN", 1, 1, "CFE-1", uuid.Nil, "")
+
+	assert.Nil(t, tx)
+	assert.ErrorIs(t, err, domain.ErrMerchantUnavailable)
+	mockRepo.AssertNotCalled(t, "CreateTransaction", mock.Anything)
+	mockRepo.AssertNotCalled(t, "PostJournalEntry", mock.Anything)
+	connector.AssertNotCalled(t, "NotifyPayment", mock.Anything, mock.Anything)
 }
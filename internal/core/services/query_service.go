@@ -0,0 +1,95 @@
+package services
+
+import (
+	"github.com/google/uuid"
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/scorazag/gopayhub/internal/core/ports"
+)
+
+// queryService consulta operaciones ya hechas. El repositorio filtra por cliente:
+// un registro de otro cliente se ve igual que uno que no existe (domain.ErrNotFound).
+type queryService struct {
+	repo ports.PaymentRepository
+}
+
+func NewQueryService(repo ports.PaymentRepository) ports.QueryService {
+	return &queryService{repo: repo}
+}
+
+func (s *queryService) GetTransaction(id uuid.UUID, clientID uint) (*domain.Transaction, error) {
+	return s.repo.GetTransaction(id, clientID)
+}
+
+func (s *queryService) GetDeposit(id uuid.UUID, clientID uint) (*domain.Deposit, error) {
+	return s.repo.GetDeposit(id, clientID)
+}
+
+func (s *queryService) GetCashOut(id uuid.UUID, clientID uint) (*domain.CashOut, error) {
+	return s.repo.GetCashOut(id, clientID)
+}
+
+func (s *queryService) GetTransactionByIdempotencyKey(key string, clientID uint) (*domain.Transaction, error) {
+	// Las operaciones sin llave la guardan vacía: una llave vacía no debe encontrarlas
+	if key == "" {
+		return nil, domain.ErrNotFound
+	}
+	return s.repo.GetTransactionByIdempotencyKey(key, clientID)
+}
+
+func (s *queryService) GetDepositByIdempotencyKey(key string, clientID uint) (*domain.Deposit, error) {
+	if key == "" {
+		return nil, domain.ErrNotFound
+	}
+	return s.repo.GetDepositByIdempotencyKey(key, clientID)
+}
+
+func (s *queryService) GetCashOutByIdempotencyKey(key string, clientID uint) (*domain.CashOut, error) {
+	if key == "" {
+		return nil, domain.ErrNotFound
+	}
+	return s.repo.GetCashOutByIdempotencyKey(key, clientID)
+}
+
+func (s *queryService) ListTransactions(f domain.OperationFilter) (*domain.Page[domain.Transaction], error) {
+	return listPage(f, s.repo.ListTransactions, func(tx domain.Transaction) domain.PageCursor {
+		return domain.PageCursor{CreatedAt: tx.CreatedAt, ID: tx.ID}
+	})
+}
+
+func (s *queryService) ListDeposits(f domain.OperationFilter) (*domain.Page[domain.Deposit], error) {
+	return listPage(f, s.repo.ListDeposits, func(d domain.Deposit) domain.PageCursor {
+		return domain.PageCursor{CreatedAt: d.CreatedAt, ID: d.ID}
+	})
+}
+
+func (s *queryService) ListCashOuts(f domain.OperationFilter) (*domain.Page[domain.CashOut], error) {
+	return listPage(f, s.repo.ListCashOuts, func(c domain.CashOut) domain.PageCursor {
+		return domain.PageCursor{CreatedAt: c.CreatedAt, ID: c.ID}
+	})
+}
+
+// listPage pide un registro de más para saber si hay otra página sin contar toda la tabla
+func listPage[T any](f domain.OperationFilter, list func(domain.OperationFilter) ([]T, error), cursor func(T) domain.PageCursor) (*domain.Page[T], error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	pageSize := f.Limit
+	if pageSize == 0 {
+		pageSize = domain.DefaultPageSize
+	}
+	f.Limit = pageSize + 1
+	items, err := list(f)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &domain.Page[T]{Items: items}
+	if len(items) > pageSize {
+		page.Items = items[:pageSize]
+		page.NextCursor = cursor(page.Items[pageSize-1]).Encode()
+	}
+	if page.Items == nil {
+		page.Items = []T{} // En JSON una página vacía es [] y no null
+	}
+	return page, nil
+}
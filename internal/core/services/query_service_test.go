@@ -0,0 +1,85 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestQueryService_ScopedToClient(t *testing.T) {
+	mockRepo := new(MockRepo)
+	service := NewQueryService(mockRepo)
+	id := uuid.New()
+
+	// El repositorio solo encuentra la transacción para su dueño
+	mockRepo.On("GetTransaction", id, uint(1)).Return(&domain.Transaction{ID: id, ClientID: 1}, nil)
+	mockRepo.On("GetTransaction", id, uint(2)).Return(nil, domain.ErrNotFound)
+
+	tx, err := service.GetTransaction(id, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, id, tx.ID)
+
+	other, err := service.GetTransaction(id, 2)
+	assert.Nil(t, other)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestQueryService_EmptyIdempotencyKeyIsNotFound(t *testing.T) {
+	mockRepo := new(MockRepo)
+	service := NewQueryService(mockRepo)
+
+	// Las operaciones hechas sin llave no deben salir buscando una llave vacía
+	_, err := service.GetDepositByIdempotencyKey("", 1)
+
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	mockRepo.AssertNotCalled(t, "GetDepositByIdempotencyKey", mock.Anything, mock.Anything)
+}
+
+func TestQueryService_ListPaginates(t *testing.T) {
+	mockRepo := new(MockRepo)
+	service := NewQueryService(mockRepo)
+	now := time.Now()
+	deposits := []domain.Deposit{
+		{ID: uuid.New(), ClientID: 1, CreatedAt: now},
+		{ID: uuid.New(), ClientID: 1, CreatedAt: now.Add(-time.Minute)},
+		{ID: uuid.New(), ClientID: 1, CreatedAt: now.Add(-2 * time.Minute)},
+	}
+	// Se pide un registro de más para saber si hay otra página
+	mockRepo.On("ListDeposits", mock.MatchedBy(func(f domain.OperationFilter) bool {
+		return f.ClientID == 1 && f.Limit == 3
+	})).Return(deposits, nil)
+
+	page, err := service.ListDeposits(domain.OperationFilter{ClientID: 1, Limit: 2})
+
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 2)
+	cursor, err := domain.DecodePageCursor(page.NextCursor)
+	assert.NoError(t, err)
+	assert.Equal(t, deposits[1].ID, cursor.ID)
+	assert.True(t, deposits[1].CreatedAt.Equal(cursor.CreatedAt))
+}
+
+func TestQueryService_ListLastPageHasNoCursor(t *testing.T) {
+	mockRepo := new(MockRepo)
+	service := NewQueryService(mockRepo)
+	mockRepo.On("ListCashOuts", mock.Anything).Return([]domain.CashOut(nil), nil)
+
+	page, err := service.ListCashOuts(domain.OperationFilter{ClientID: 1})
+
+	assert.NoError(t, err)
+	assert.Empty(t, page.NextCursor)
+	assert.NotNil(t, page.Items)
+}
+
+func TestQueryService_ListRejectsInvalidRanges(t *testing.T) {
+	service := NewQueryService(new(MockRepo))
+	minAmount, maxAmount := domain.MoneyFromUnits(500), domain.MoneyFromUnits(100)
+
+	_, err := service.ListTransactions(domain.OperationFilter{ClientID: 1, MinAmount: &minAmount, MaxAmount: &maxAmount})
+
+	assert.Error(t, err)
+}
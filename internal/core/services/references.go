@@ -0,0 +1,39 @@
+package services
+
+import (
+	"strings"
+	"time"
+
+	"github.com/scorazag/gopayhub/internal/core/domain"
+)
+
+// ReferenceValidator revisa la referencia con la regla del merchant (o de su tipo de servicio)
+// antes de crear la transacción, para que un código mal escaneado no llegue al biller.
+type ReferenceValidator struct {
+	rules domain.ReferenceRules
+	now   func() time.Time
+}
+
+// NewReferenceValidator recibe la zona del día hábil: la fecha límite de un recibo es un día local
+func NewReferenceValidator(rules domain.ReferenceRules, loc *time.Location) (*ReferenceValidator, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+	return &ReferenceValidator{rules: rules, now: func() time.Time { return time.Now().In(loc) }}, nil
+}
+
+// Validate revisa la referencia; amount cero omite revisar el monto incluido en ella.
+// Sin reglas (validador nil o merchant sin regla) solo se exige que no esté vacía.
+func (v *ReferenceValidator) Validate(merchant *domain.Merchant, reference string, amount domain.Money) error {
+	if strings.TrimSpace(reference) == "" {
+		return domain.ErrReferenceRequired
+	}
+	if v == nil {
+		return nil
+	}
+	rule, ok := v.rules.For(merchant)
+	if !ok {
+		return nil
+	}
+	return rule.Validate(reference, amount, v.now())
+}
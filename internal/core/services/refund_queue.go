@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/scorazag/gopayhub/internal/core/ports"
+)
+
+// ProcessQueuedRefund toma la siguiente devolución que el biller no contestó y le vuelve a preguntar.
+// Igual que con los pagos no hay límite de intentos: el biller la reconoce por el ID de la devolución
+// y solo un rechazo explícito la deja FAILED.
+func (s *refundService) ProcessQueuedRefund() (bool, error) {
+	job, err := s.repo.ClaimRefundJob(s.now(), paymentJobLease)
+	if err != nil || job == nil {
+		return false, err
+	}
+
+	// Si algo falla antes de preguntarle al biller, el job se queda apartado y otro worker lo retoma al vencer
+	refund, err := s.repo.GetRefund(job.RefundID)
+	if err != nil {
+		return true, err
+	}
+	if refund.Status != "PENDING" {
+		// Ya la terminó otro worker al que se le venció el apartado
+		return true, s.repo.DeleteRefundJob(job.ID)
+	}
+	tx, err := s.repo.GetTransaction(refund.TransactionID, job.ClientID)
+	if err != nil {
+		return true, err
+	}
+	merchant, err := s.repo.GetMerchantByID(tx.MerchantID)
+	if err != nil {
+		return true, err
+	}
+
+	notifyErr := s.connector.NotifyRefund(merchant, tx, refund)
+	if notifyErr != nil && !errors.Is(notifyErr, domain.ErrRefundRejected) {
+		if job.Attempts >= paymentReconcileAttempts {
+			log.Printf("[devoluciones] ALERTA: la devolución %s lleva %d intentos sin respuesta del biller %d; revisar con el biller: %v", refund.ID, job.Attempts, merchant.ID, notifyErr)
+		}
+		return true, s.retryLater(job, notifyErr)
+	}
+
+	// El rechazo del biller es el resultado de la devolución, no un error del worker
+	if err := s.settle(refund, notifyErr, job); err != nil && !errors.Is(err, domain.ErrRefundRejected) {
+		return true, err
+	}
+	return true, nil
+}
+
+// retryLater regresa el job a la cola con la misma espera creciente que los pagos
+func (s *refundService) retryLater(job *domain.RefundJob, cause error) error {
+	job.RunAt = s.now().Add(retryDelay(job.Attempts))
+	job.LockedUntil = nil
+	job.LastError = truncate(cause.Error(), 300)
+	return s.repo.SaveRefundJob(job)
+}
+
+// RunRefundWorkers arranca los workers de la cola de devoluciones, igual que RunPaymentWorkers
+func RunRefundWorkers(ctx context.Context, service ports.RefundService, workers int, idle time.Duration) {
+	runWorkers(ctx, "devoluciones", service.ProcessQueuedRefund, workers, idle)
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// queuedRefund configura el mock con un job apartado para una devolución PENDING de 200 sobre un pago de 500
+func queuedRefund(mockRepo *MockRepo) (*domain.RefundJob, *domain.Refund, *domain.Transaction) {
+	tx := completedTx()
+	tx.RefundedAmount = domain.MustParseMoney("200.00")
+	refund := &domain.Refund{ID: uuid.New(), TransactionID: tx.ID, Amount: domain.MustParseMoney("200.00"), Status: "PENDING", ClientID: 1}
+	job := &domain.RefundJob{ID: 5, RefundID: refund.ID, ClientID: 1, Attempts: 2}
+	mockRepo.On("ClaimRefundJob", mock.Anything, paymentJobLease).Return(job, nil)
+	mockRepo.On("GetRefund", refund.ID).Return(refund, nil)
+	mockRepo.On("GetTransaction", tx.ID, uint(1)).Return(tx, nil)
+	mockRepo.On("GetTransactionForUpdate", tx.ID).Return(tx, nil)
+	mockRepo.On("GetMerchantByID", uint(2)).Return(&domain.Merchant{ID: 2, Name: "CFE"}, nil)
+	mockRepo.On("SaveRefund", refund).Return(nil)
+	mockRepo.On("SaveTransaction", tx).Return(nil)
+	mockRepo.On("PostJournalEntry", mock.Anything).Return(nil)
+	return job, refund, tx
+}
+
+func TestProcessQueuedRefund_ApprovedCompletes(t *testing.T) {
+	mockRepo := new(MockRepo)
+	connector := new(MockConnector)
+	service := NewRefundService(mockRepo, connector)
+	job, refund, tx := queuedRefund(mockRepo)
+	mockRepo.On("DeleteRefundJob", job.ID).Return(nil)
+	connector.On("NotifyRefund", mock.Anything, tx, refund).Return(nil)
+
+	found, err := service.ProcessQueuedRefund()
+
+	assert.True(t, found)
+	assert.NoError(t, err)
+	assert.Equal(t, "COMPLETED", refund.Status)
+	assert.Equal(t, "PARTIALLY_REFUNDED", tx.Status)
+	mockRepo.AssertCalled(t, "PostJournalEntry", mock.Anything)
+}
+
+func TestProcessQueuedRefund_BillerUnavailableRetriesLater(t *testing.T) {
+	mockRepo := new(MockRepo)
+	connector := new(MockConnector)
+	service := NewRefundService(mockRepo, connector).(*refundService)
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+	job, refund, tx := queuedRefund(mockRepo)
+	mockRepo.On("SaveRefundJob", job).Return(nil)
+	connector.On("NotifyRefund", mock.Anything, tx, refund).Return(fmt.Errorf("%w: CFE no contestó: timeout", domain.ErrMerchantUnavailable))
+
+	found, err := service.ProcessQueuedRefund()
+
+	// Se le vuelve a preguntar con el mismo ID; el monto sigue apartado
+	assert.True(t, found)
+	assert.NoError(t, err)
+	assert.Equal(t, "PENDING", refund.Status)
+	assert.Equal(t, domain.MustParseMoney("200.00"), tx.RefundedAmount)
+	assert.Equal(t, now.Add(2*paymentRetryDelay), job.RunAt)
+	assert.Nil(t, job.LockedUntil)
+	mockRepo.AssertNotCalled(t, "DeleteRefundJob", mock.Anything)
+}
+
+func TestProcessQueuedRefund_RejectedFailsAndReleases(t *testing.T) {
+	mockRepo := new(MockRepo)
+	connector := new(MockConnector)
+	service := NewRefundService(mockRepo, connector)
+	job, refund, tx := queuedRefund(mockRepo)
+	mockRepo.On("DeleteRefundJob", job.ID).Return(nil)
+	connector.On("NotifyRefund", mock.Anything, tx, refund).Return(fmt.Errorf("%w: CFE: pago ya aplicado", domain.ErrRefundRejected))
+
+	found, err := service.ProcessQueuedRefund()
+
+	// El rechazo es el resultado de la devolución, no un error del worker
+	assert.True(t, found)
+	assert.NoError(t, err)
+	assert.Equal(t, "FAILED", refund.Status)
+	assert.Equal(t, domain.Money(0), tx.RefundedAmount)
+	mockRepo.AssertNotCalled(t, "PostJournalEntry", mock.Anything)
+}
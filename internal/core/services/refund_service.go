@@ -0,0 +1,179 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/scorazag/gopayhub/internal/core/ports"
+)
+
+type refundService struct {
+	repo      ports.PaymentRepository
+	connector ports.MerchantConnector
+	now       func() time.Time
+}
+
+func NewRefundService(repo ports.PaymentRepository, connector ports.MerchantConnector) ports.RefundService {
+	return &refundService{repo: repo, connector: connector, now: time.Now}
+}
+
+func (s *refundService) RefundPayment(transactionID uuid.UUID, amount domain.Money, clientID uint, reason string, idemKey string) (*domain.Refund, error) {
+	// 1. REGLAS DE NEGOCIO
+	if amount < 0 {
+		return nil, domain.ErrInvalidAmount
+	}
+
+	// 2-5. Se aparta el monto y la devolución queda PENDING con su job; el saldo se le regresa al cliente hasta que el biller la acepte
+	refund, tx, merchant, job, err := s.createPending(transactionID, amount, clientID, reason, idemKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// 6. AVISAR AL BILLER (fuera de la transacción de BD para no retener el bloqueo durante la llamada)
+	// Desde aquí la devolución ya está guardada con su job: si no se puede terminar aquí, la termina la cola
+	notifyErr := s.connector.NotifyRefund(merchant, tx, refund)
+	if notifyErr != nil && !errors.Is(notifyErr, domain.ErrRefundRejected) {
+		// Sin respuesta no sabemos si el biller la aplicó: se queda PENDING con el monto apartado
+		// y se le vuelve a preguntar con el mismo ID, para que no la aplique dos veces
+		if err := s.retryLater(job, notifyErr); err != nil {
+			log.Printf("[devoluciones] la devolución %s queda apartada hasta que venza su job: %v", refund.ID, err)
+		}
+		return refund, nil
+	}
+	if err := s.settle(refund, notifyErr, job); err != nil {
+		var coded domain.CodedError
+		if errors.As(err, &coded) {
+			return nil, err
+		}
+		// El biller contestó pero no se pudo guardar: el job sigue ahí y la cola le vuelve a preguntar
+		log.Printf("[devoluciones] no se pudo guardar la respuesta del biller para la devolución %s: %v", refund.ID, err)
+		refund.Status, refund.FailureReason = "PENDING", ""
+	}
+	return refund, nil
+}
+
+// createPending valida la devolución, aparta el monto en la transacción original y guarda la devolución en PENDING.
+// Su job queda apartado por esta petición mientras le pregunta al biller; si el proceso se cae, lo retoma la cola.
+func (s *refundService) createPending(transactionID uuid.UUID, amount domain.Money, clientID uint, reason string, idemKey string) (*domain.Refund, *domain.Transaction, *domain.Merchant, *domain.RefundJob, error) {
+	var refund *domain.Refund
+	var job *domain.RefundJob
+	var tx *domain.Transaction
+	var merchant *domain.Merchant
+	err := s.repo.Atomic(func(repo ports.PaymentRepository) error {
+		// 2. Bloqueamos la transacción original para que dos devoluciones no rebasen el monto pagado
+		var err error
+		tx, err = repo.GetTransactionForUpdate(transactionID)
+		if err != nil {
+			return notFoundAs(err, domain.ErrTransactionNotFound)
+		}
+		if tx.ClientID != clientID {
+			return domain.ErrTransactionNotFound
+		}
+		// Solo lo que el biller ya aplicó; un pago PENDING todavía no se sabe si lo aplicó
+		if tx.Status != "COMPLETED" && tx.Status != "PARTIALLY_REFUNDED" {
+			return domain.ErrNotRefundable
+		}
+
+		// 3. Sin monto = devolución total de lo que resta (lo que está PENDING ya no se puede devolver)
+		remaining := tx.Amount - tx.RefundedAmount
+		if amount == 0 {
+			amount = remaining
+		}
+		if amount == 0 || amount > remaining {
+			return domain.ErrRefundExceedsBalance
+		}
+
+		merchant, err = repo.GetMerchantByID(tx.MerchantID)
+		if err != nil {
+			return notFoundAs(err, domain.ErrMerchantNotFound)
+		}
+
+		// 4. Si el pago se liquidó en otra moneda, devolvemos al mismo tipo de cambio
+		var conversion domain.FXConversion
+		if tx.SettlementCurrency != "" && tx.SettlementCurrency != tx.Currency {
+			quote := &domain.FXQuote{From: tx.Currency, To: tx.SettlementCurrency, Rate: tx.FXRate, Source: tx.FXSource}
+			if tx.FXRateAt != nil {
+				quote.AsOf = *tx.FXRateAt
+			}
+			if conversion, err = domain.NewFXConversion(amount, quote); err != nil {
+				return err
+			}
+		}
+
+		// 5. Guardar la devolución y apartar el monto en la original
+		refund = &domain.Refund{
+			TransactionID:  tx.ID,
+			Amount:         amount,
+			Currency:       tx.Currency,
+			Status:         "PENDING",
+			Reason:         reason,
+			FXConversion:   conversion,
+			ClientID:       clientID,
+			IdempotencyKey: idemKey,
+		}
+		if err := repo.CreateRefund(refund); err != nil {
+			return err
+		}
+		tx.RefundedAmount += amount
+		if err := repo.SaveTransaction(tx); err != nil {
+			return err
+		}
+		lockedUntil := s.now().Add(paymentJobLease)
+		job = &domain.RefundJob{RefundID: refund.ID, ClientID: clientID, Attempts: 1, RunAt: s.now(), LockedUntil: &lockedUntil}
+		return repo.EnqueueRefundJob(job)
+	})
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	return refund, tx, merchant, job, nil
+}
+
+// settle guarda la respuesta del biller: si aceptó, la devolución queda COMPLETED y se le abona al cliente;
+// si la rechazó (rejection), queda FAILED y se libera el monto apartado. Solo se llama con una respuesta.
+// Regresa el error de negocio que ve el cliente; el job se borra en la misma transacción de BD.
+func (s *refundService) settle(refund *domain.Refund, rejection error, job *domain.RefundJob) error {
+	err := s.repo.Atomic(func(repo ports.PaymentRepository) error {
+		if err := repo.DeleteRefundJob(job.ID); err != nil {
+			return err
+		}
+		// Volvemos a leer la original: otra devolución pudo cambiarla mientras el biller contestaba
+		tx, err := repo.GetTransactionForUpdate(refund.TransactionID)
+		if err != nil {
+			return err
+		}
+
+		if rejection != nil {
+			refund.Status = "FAILED"
+			refund.FailureReason = truncate(rejection.Error(), 200)
+			if err := repo.SaveRefund(refund); err != nil {
+				return err
+			}
+			tx.RefundedAmount -= refund.Amount
+			// Si ya había otra devolución completa, la original sigue parcialmente devuelta
+			if tx.Status == "REFUNDED" {
+				tx.Status = "PARTIALLY_REFUNDED"
+			}
+			return repo.SaveTransaction(tx)
+		}
+
+		refund.Status = "COMPLETED"
+		if err := repo.SaveRefund(refund); err != nil {
+			return err
+		}
+		tx.Status = "PARTIALLY_REFUNDED"
+		if tx.RefundedAmount == tx.Amount {
+			tx.Status = "REFUNDED"
+		}
+		if err := repo.SaveTransaction(tx); err != nil {
+			return err
+		}
+		return repo.PostJournalEntry(domain.NewRefundEntry(refund, tx))
+	})
+	if err != nil {
+		return err
+	}
+	return rejection
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockConnector struct {
+	mock.Mock
+}
+
+func (m *MockConnector) NotifyPayment(merchant *domain.Merchant, tx *domain.Transaction) (*domain.PaymentConfirmation, error) {
+	args := m.Called(merchant, tx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.PaymentConfirmation), args.Error(1)
+}
+
+func (m *MockConnector) InquireBill(merchant *domain.Merchant, reference string) (*domain.Bill, error) {
+	args := m.Called(merchant, reference)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Bill), args.Error(1)
+}
+
+func (m *MockConnector) NotifyRefund(merchant *domain.Merchant, tx *domain.Transaction, refund *domain.Refund) error {
+	return m.Called(merchant, tx, refund).Error(0)
+}
+
+func completedTx() *domain.Transaction {
+	return &domain.Transaction{
+		ID:         uuid.New(),
+		Amount:     domain.MustParseMoney("500.00"),
+		Status:     "COMPLETED",
+		Reference:  "CFE-123",
+		ClientID:   1,
+		MerchantID: 2,
+	}
+}
+
+func TestRefundPayment_Partial(t *testing.T) {
+	mockRepo := new(MockRepo)
+	mockConnector := new(MockConnector)
+	service := NewRefundService(mockRepo, mockConnector)
+	tx := completedTx()
+
+	mockRepo.On("GetTransactionForUpdate", tx.ID).Return(tx, nil)
+	mockRepo.On("GetMerchantByID", uint(2)).Return(&domain.Merchant{ID: 2, Name: "CFE"}, nil)
+	mockRepo.On("CreateRefund", mock.MatchedBy(func(r *domain.Refund) bool { return r.Status == "PENDING" })).Return(nil)
+	mockRepo.On("SaveRefund", mock.Anything).Return(nil)
+	refundJobs(mockRepo)
+	mockRepo.On("SaveTransaction", tx).Return(nil)
+	mockRepo.On("PostJournalEntry", mock.MatchedBy(func(e *domain.JournalEntry) bool {
+		// La devolución abona al cliente
+		return e.OperationType == domain.OperationRefund && e.Postings[1].AccountCode == domain.ClientAccountCode(1)
+	})).Return(nil)
+	mockConnector.On("NotifyRefund", mock.Anything, tx, mock.Anything).Return(nil)
+
+	refund, err := service.RefundPayment(tx.ID, domain.MustParseMoney("200.00"), 1, "cobro duplicado", "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.MustParseMoney("200.00"), refund.Amount)
+	assert.Equal(t, tx.ID, refund.TransactionID)
+	assert.Equal(t, "COMPLETED", refund.Status)
+	assert.Equal(t, "PARTIALLY_REFUNDED", tx.Status)
+	assert.Equal(t, domain.MustParseMoney("200.00"), tx.RefundedAmount)
+	mockRepo.AssertExpectations(t)
+	mockConnector.AssertExpectations(t)
+}
+
+func TestRefundPayment_FullWhenAmountOmitted(t *testing.T) {
+	mockRepo := new(MockRepo)
+	mockConnector := new(MockConnector)
+	service := NewRefundService(mockRepo, mockConnector)
+	tx := completedTx()
+	tx.Status = "PARTIALLY_REFUNDED"
+	tx.RefundedAmount = domain.MustParseMoney("100.00")
+
+	mockRepo.On("GetTransactionForUpdate", tx.ID).Return(tx, nil)
+	mockRepo.On("GetMerchantByID", uint(2)).Return(&domain.Merchant{ID: 2}, nil)
+	mockRepo.On("CreateRefund", mock.MatchedBy(func(r *domain.Refund) bool { return r.Status == "PENDING" })).Return(nil)
+	mockRepo.On("SaveRefund", mock.Anything).Return(nil)
+	refundJobs(mockRepo)
+	mockRepo.On("SaveTransaction", tx).Return(nil)
+	mockRepo.On("PostJournalEntry", mock.Anything).Return(nil)
+	mockConnector.On("NotifyRefund", mock.Anything, tx, mock.Anything).Return(nil)
+
+	refund, err := service.RefundPayment(tx.ID, 0, 1, "", "")
+
+	// Se devuelve solo lo que restaba
+	assert.NoError(t, err)
+	assert.Equal(t, domain.MustParseMoney("400.00"), refund.Amount)
+	assert.Equal(t, "REFUNDED", tx.Status)
+}
+
+func TestRefundPayment_RejectedByBillerReleasesAmount(t *testing.T) {
+	mockRepo := new(MockRepo)
+	mockConnector := new(MockConnector)
+	service := NewRefundService(mockRepo, mockConnector)
+	tx := completedTx()
+
+	mockRepo.On("GetTransactionForUpdate", tx.ID).Return(tx, nil)
+	mockRepo.On("GetMerchantByID", uint(2)).Return(&domain.Merchant{ID: 2, Name: "CFE"}, nil)
+	mockRepo.On("CreateRefund", mock.Anything).Return(nil)
+	mockRepo.On("SaveRefund", mock.Anything).Return(nil)
+	refundJobs(mockRepo)
+	mockRepo.On("SaveTransaction", tx).Return(nil)
+	// Mientras se le pregunta al biller el monto ya está apartado
+	mockConnector.On("NotifyRefund", mock.Anything, tx, mock.Anything).Run(func(args mock.Arguments) {
+		assert.Equal(t, domain.MustParseMoney("200.00"), tx.RefundedAmount)
+	}).Return(fmt.Errorf("%w: CFE: pago ya aplicado", domain.ErrRefundRejected))
+
+	refund, err := service.RefundPayment(tx.ID, domain.MustParseMoney("200.00"), 1, "", "")
+
+	assert.Nil(t, refund)
+	assert.ErrorIs(t, err, domain.ErrRefundRejected)
+	mockRepo.AssertCalled(t, "DeleteRefundJob", uint(5))
+	mockRepo.AssertCalled(t, "SaveRefund", mock.MatchedBy(func(r *domain.Refund) bool {
+		return r.Status == "FAILED" && r.FailureReason != ""
+	}))
+	// El cliente no recibe abono y el monto se puede volver a devolver
+	mockRepo.AssertNotCalled(t, "PostJournalEntry", mock.Anything)
+	assert.Equal(t, domain.Money(0), tx.RefundedAmount)
+	assert.Equal(t, "COMPLETED", tx.Status)
+}
+
+func TestRefundPayment_BillerUnavailableStaysPending(t *testing.T) {
+	mockRepo := new(MockRepo)
+	mockConnector := new(MockConnector)
+	service := NewRefundService(mockRepo, mockConnector)
+	tx := completedTx()
+
+	mockRepo.On("GetTransactionForUpdate", tx.ID).Return(tx, nil)
+	mockRepo.On("GetMerchantByID", uint(2)).Return(&domain.Merchant{ID: 2, Name: "CFE"}, nil)
+	mockRepo.On("CreateRefund", mock.Anything).Return(nil)
+	mockRepo.On("SaveTransaction", tx).Return(nil)
+	refundJobs(mockRepo)
+	mockConnector.On("NotifyRefund", mock.Anything, tx, mock.Anything).Return(fmt.Errorf("%w: CFE contestó HTTP 502", domain.ErrMerchantUnavailable))
+
+	refund, err := service.RefundPayment(tx.ID, domain.MustParseMoney("200.00"), 1, "", "")
+
+	// El biller pudo haberla aplicado: ni FAILED ni se libera el monto; la cola le vuelve a preguntar
+	assert.NoError(t, err)
+	assert.Equal(t, "PENDING", refund.Status)
+	assert.Equal(t, domain.MustParseMoney("200.00"), tx.RefundedAmount)
+	mockRepo.AssertNotCalled(t, "SaveRefund", mock.Anything)
+	mockRepo.AssertNotCalled(t, "PostJournalEntry", mock.Anything)
+	mockRepo.AssertNotCalled(t, "DeleteRefundJob", mock.Anything)
+	mockRepo.AssertCalled(t, "SaveRefundJob", mock.MatchedBy(func(job *domain.RefundJob) bool {
+		return job.LockedUntil == nil && job.RunAt.After(time.Now()) && job.LastError != ""
+	}))
+}
+
+func TestRefundPayment_ExceedsRemaining(t *testing.T) {
+	mockRepo := new(MockRepo)
+	mockConnector := new(MockConnector)
+	service := NewRefundService(mockRepo, mockConnector)
+	tx := completedTx()
+
+	mockRepo.On("GetTransactionForUpdate", tx.ID).Return(tx, nil)
+
+	refund, err := service.RefundPayment(tx.ID, domain.MustParseMoney("500.01"), 1, "", "")
+
+	assert.Nil(t, refund)
+	assert.Equal(t, "el monto excede lo que resta por devolver de la transacción", err.Error())
+	mockRepo.AssertNotCalled(t, "CreateRefund", mock.Anything)
+	mockConnector.AssertNotCalled(t, "NotifyRefund", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRefundPayment_OtherClientsTransaction(t *testing.T) {
+	mockRepo := new(MockRepo)
+	service := NewRefundService(mockRepo, new(MockConnector))
+	tx := completedTx()
+
+	mockRepo.On("GetTransactionForUpdate", tx.ID).Return(tx, nil)
+
+	// El cliente 99 no puede ver ni devolver la transacción del cliente 1
+	_, err := service.RefundPayment(tx.ID, 0, 99, "", "")
+
+	assert.Equal(t, "transacción no encontrada", err.Error())
+}
+
+// refundJobs acepta el job que se guarda con cada devolución; la BD le pone el ID 5
+func refundJobs(mockRepo *MockRepo) {
+	mockRepo.On("EnqueueRefundJob", mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(0).(*domain.RefundJob).ID = 5
+	}).Return(nil)
+	mockRepo.On("SaveRefundJob", mock.Anything).Return(nil).Maybe()
+	mockRepo.On("DeleteRefundJob", uint(5)).Return(nil).Maybe()
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/scorazag/gopayhub/internal/core/ports"
+)
+
+// applyTaxes agrega a cada comisión el desglose de IVA según la región fiscal del cliente
+func applyTaxes(repo ports.PaymentRepository, clientID uint, fees []domain.Fee) error {
+	if len(fees) == 0 {
+		return nil
+	}
+
+	client, err := repo.GetClientByID(clientID)
+	if err != nil {
+		return err
+	}
+	region := client.Region
+	if region == "" {
+		region = domain.RegionGeneral
+	}
+
+	rate, err := repo.GetTaxRate(region)
+	if errors.Is(err, domain.ErrNotFound) {
+		// Es un error de configuración nuestro, no del cliente: no debe llegarle como NOT_FOUND
+		return fmt.Errorf("la región fiscal %s no tiene tasa de IVA configurada", region)
+	}
+	if err != nil {
+		return err
+	}
+
+	for i := range fees {
+		rate.Apply(&fees[i])
+	}
+	return nil
+}
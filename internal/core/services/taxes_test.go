@@ -0,0 +1,45 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyTaxes_BorderRegion(t *testing.T) {
+	mockRepo := new(MockRepo)
+	taxRegion(mockRepo, 5, domain.RegionFronteriza, 800)
+
+	fees := []domain.Fee{{Amount: domain.MustParseMoney("12.50")}}
+	err := applyTaxes(mockRepo, 5, fees)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "IVA", fees[0].TaxName)
+	assert.Equal(t, domain.MustParseMoney("12.50"), fees[0].TaxBase)
+	assert.Equal(t, int64(800), fees[0].TaxRateBps)
+	assert.Equal(t, domain.MustParseMoney("1.00"), fees[0].TaxAmount)
+	assert.Equal(t, domain.MustParseMoney("13.50"), domain.TotalFees(fees))
+}
+
+func TestApplyTaxes_MissingRegionRate(t *testing.T) {
+	mockRepo := new(MockRepo)
+	mockRepo.On("GetClientByID", uint(5)).Return(&domain.Client{ID: 5, Region: "MARTE"}, nil)
+	mockRepo.On("GetTaxRate", "MARTE").Return(nil, domain.ErrNotFound)
+
+	err := applyTaxes(mockRepo, 5, []domain.Fee{{Amount: domain.MustParseMoney("10")}})
+
+	// No cobramos comisiones con un IVA inventado
+	assert.ErrorContains(t, err, "la región fiscal MARTE no tiene tasa de IVA configurada")
+	// Sin código: el cliente ve un 500, no un NOT_FOUND
+	var coded domain.CodedError
+	assert.False(t, errors.As(err, &coded))
+}
+
+func TestApplyTaxes_NoFeesNoLookup(t *testing.T) {
+	mockRepo := new(MockRepo)
+
+	assert.NoError(t, applyTaxes(mockRepo, 5, nil))
+	mockRepo.AssertNotCalled(t, "GetClientByID", uint(5))
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/scorazag/gopayhub/internal/core/ports"
+)
+
+// VoidPolicy define la ventana para anular: el mismo día hábil de la operación y antes del corte
+type VoidPolicy struct {
+	CutoffHour   int            // Ej: 23 para las 23:00
+	CutoffMinute int            // Ej: 0
+	Location     *time.Location // Zona horaria del día hábil. Ej: America/Mexico_City
+}
+
+// Allows dice si una operación creada en createdAt todavía se puede anular en now
+func (p VoidPolicy) Allows(createdAt, now time.Time) bool {
+	loc := p.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	created := createdAt.In(loc)
+	current := now.In(loc)
+
+	cy, cm, cd := created.Date()
+	ny, nm, nd := current.Date()
+	if cy != ny || cm != nm || cd != nd {
+		return false
+	}
+	cutoff := time.Date(ny, nm, nd, p.CutoffHour, p.CutoffMinute, 0, 0, loc)
+	return current.Before(cutoff)
+}
+
+type voidService struct {
+	repo   ports.PaymentRepository
+	policy VoidPolicy
+	now    func() time.Time
+}
+
+func NewVoidService(repo ports.PaymentRepository, policy VoidPolicy) ports.VoidService {
+	return &voidService{repo: repo, policy: policy, now: time.Now}
+}
+
+func (s *voidService) VoidDeposit(id uuid.UUID, clientID uint, voidedBy string, reason string) (*domain.Deposit, error) {
+	if voidedBy == "" || reason == "" {
+		return nil, domain.ErrVoidDetailsRequired
+	}
+
+	var deposit *domain.Deposit
+	err := s.repo.Atomic(func(repo ports.PaymentRepository) error {
+		var err error
+		deposit, err = repo.GetDepositForUpdate(id)
+		if err != nil {
+			return notFoundAs(err, domain.ErrDepositNotFound)
+		}
+		if deposit.ClientID != clientID {
+			return domain.ErrDepositNotFound
+		}
+		now := s.now()
+		if err := s.checkVoidable(deposit.Status, deposit.CreatedAt, now); err != nil {
+			return err
+		}
+
+		// Anular un depósito le quita el dinero al cliente: no puede haberlo gastado ya
+		currency, err := domain.NormalizeCurrency(deposit.Currency)
+		if err != nil {
+			return err
+		}
+		if err := repo.LockClientAccount(clientID, currency); err != nil {
+			return err
+		}
+		available, err := availableBalance(repo, clientID, currency)
+		if err != nil {
+			return err
+		}
+		// El cliente recibió el monto menos la comisión; la anulación le quita exactamente eso
+		if deposit.Amount-deposit.FeeAmount > available {
+			return domain.ErrInsufficientFunds
+		}
+
+		deposit.Status = "VOIDED"
+		deposit.VoidInfo = domain.VoidInfo{VoidedAt: &now, VoidedBy: voidedBy, VoidReason: reason}
+		if err := repo.SaveDeposit(deposit); err != nil {
+			return err
+		}
+		return repo.PostJournalEntry(domain.NewVoidEntry(domain.NewDepositEntry(deposit)))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deposit, nil
+}
+
+func (s *voidService) VoidCashOut(id uuid.UUID, clientID uint, voidedBy string, reason string) (*domain.CashOut, error) {
+	if voidedBy == "" || reason == "" {
+		return nil, domain.ErrVoidDetailsRequired
+	}
+
+	var cashout *domain.CashOut
+	err := s.repo.Atomic(func(repo ports.PaymentRepository) error {
+		var err error
+		cashout, err = repo.GetCashOutForUpdate(id)
+		if err != nil {
+			return notFoundAs(err, domain.ErrCashOutNotFound)
+		}
+		if cashout.ClientID != clientID {
+			return domain.ErrCashOutNotFound
+		}
+		now := s.now()
+		if err := s.checkVoidable(cashout.Status, cashout.CreatedAt, now); err != nil {
+			return err
+		}
+
+		// Anular un retiro regresa el dinero al saldo del cliente
+		cashout.Status = "VOIDED"
+		cashout.VoidInfo = domain.VoidInfo{VoidedAt: &now, VoidedBy: voidedBy, VoidReason: reason}
+		if err := repo.SaveCashOut(cashout); err != nil {
+			return err
+		}
+		return repo.PostJournalEntry(domain.NewVoidEntry(domain.NewCashOutEntry(cashout)))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cashout, nil
+}
+
+func (s *voidService) checkVoidable(status string, createdAt, now time.Time) error {
+	if status == "VOIDED" {
+		return domain.ErrAlreadyVoided
+	}
+	if status != "COMPLETED" {
+		return domain.ErrNotVoidable
+	}
+	if !s.policy.Allows(createdAt, now) {
+		return domain.ErrVoidWindowClosed
+	}
+	return nil
+}
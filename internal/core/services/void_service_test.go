@@ -0,0 +1,90 @@
+package services
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/google/uuid"
+	"github.com/scorazag/gopayhub/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var mexicoCity, _ = time.LoadLocation("America/Mexico_City")
+
+// Corte a las 22:00 hora del centro
+var testVoidPolicy = VoidPolicy{CutoffHour: 22, Location: mexicoCity}
+
+func newTestVoidService(repo *MockRepo, now time.Time) *voidService {
+	s := NewVoidService(repo, testVoidPolicy).(*voidService)
+	s.now = func() time.Time { return now }
+	return s
+}
+
+func TestVoidPolicy_Allows(t *testing.T) {
+	created := time.Date(2025, 3, 10, 9, 0, 0, 0, mexicoCity)
+
+	assert.True(t, testVoidPolicy.Allows(created, created.Add(2*time.Hour)))
+	// Después del corte ya no
+	assert.False(t, testVoidPolicy.Allows(created, time.Date(2025, 3, 10, 22, 0, 0, 0, mexicoCity)))
+	// Al día siguiente tampoco, aunque sea temprano
+	assert.False(t, testVoidPolicy.Allows(created, time.Date(2025, 3, 11, 8, 0, 0, 0, mexicoCity)))
+}
+
+func TestVoidCashOut_WithinWindow(t *testing.T) {
+	mockRepo := new(MockRepo)
+	created := time.Date(2025, 3, 10, 9, 0, 0, 0, mexicoCity)
+	service := newTestVoidService(mockRepo, created.Add(time.Hour))
+
+	cashout := &domain.CashOut{ID: uuid.New(), Amount: domain.MustParseMoney("300"), ClientID: 1, Status: "COMPLETED", CreatedAt: created}
+	mockRepo.On("GetCashOutForUpdate", cashout.ID).Return(cashout, nil)
+	mockRepo.On("SaveCashOut", cashout).Return(nil)
+	mockRepo.On("PostJournalEntry", mock.MatchedBy(func(e *domain.JournalEntry) bool {
+		// La anulación regresa el dinero al cliente
+		return e.OperationType == domain.OperationVoid && e.OperationID == cashout.ID &&
+			e.Postings[0].AccountCode == domain.ClientAccountCode(1) && e.Postings[0].Direction == domain.Credit
+	})).Return(nil)
+
+	res, err := service.VoidCashOut(cashout.ID, 1, "Cajero 3", "monto capturado mal")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "VOIDED", res.Status)
+	assert.Equal(t, "Cajero 3", res.VoidedBy)
+	assert.Equal(t, "monto capturado mal", res.VoidReason)
+	assert.NotNil(t, res.VoidedAt)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestVoidDeposit_WindowClosed(t *testing.T) {
+	mockRepo := new(MockRepo)
+	created := time.Date(2025, 3, 10, 9, 0, 0, 0, mexicoCity)
+	service := newTestVoidService(mockRepo, created.Add(24*time.Hour))
+
+	deposit := &domain.Deposit{ID: uuid.New(), Amount: domain.MustParseMoney("300"), ClientID: 1, Status: "COMPLETED", CreatedAt: created}
+	mockRepo.On("GetDepositForUpdate", deposit.ID).Return(deposit, nil)
+
+	_, err := service.VoidDeposit(deposit.ID, 1, "Cajero 3", "error")
+
+	assert.Equal(t, "la ventana para anular la operación ya cerró", err.Error())
+	mockRepo.AssertNotCalled(t, "SaveDeposit", mock.Anything)
+}
+
+func TestVoidDeposit_AlreadySpent(t *testing.T) {
+	mockRepo := new(MockRepo)
+	created := time.Date(2025, 3, 10, 9, 0, 0, 0, mexicoCity)
+	service := newTestVoidService(mockRepo, created.Add(time.Hour))
+
+	deposit := &domain.Deposit{ID: uuid.New(), Amount: domain.MustParseMoney("300"), ClientID: 1, Status: "COMPLETED", CreatedAt: created}
+	mockRepo.On("GetDepositForUpdate", deposit.ID).Return(deposit, nil)
+	mockRepo.On("LockClientAccount", uint(1), "MXN").Return(nil)
+	// El cliente ya gastó parte del depósito
+	mockRepo.On("GetClientBalance", uint(1), "MXN").Return(domain.MustParseMoney("100"), nil)
+	mockRepo.On("GetClientHolds", uint(1), "MXN").Return(domain.Money(0), nil)
+
+	_, err := service.VoidDeposit(deposit.ID, 1, "Cajero 3", "error")
+
+	assert.ErrorIs(t, err, domain.ErrInsufficientFunds)
+	assert.Equal(t, "COMPLETED", deposit.Status)
+	mockRepo.AssertNotCalled(t, "SaveDeposit", mock.Anything)
+}